@@ -1,21 +1,30 @@
 package main
 
 import (
+    "crypto/ed25519"
     "encoding/json"
     "flag"
     "fmt"
     "io/ioutil"
     "log"
     "os"
+    "path/filepath"
+    "strconv"
+    "strings"
     "time"
 
     "inspector/internal/blocks"
+    "inspector/internal/chainspec"
+    "inspector/internal/codec"
     "inspector/internal/config"
     "inspector/internal/consensus"
     "inspector/internal/db"
     "inspector/internal/errors"
+    "inspector/internal/keyspace"
     "inspector/internal/report"
     "inspector/internal/rpc"
+    "inspector/internal/rules"
+    "inspector/internal/validators"
     "inspector/internal/watcher"
 )
 
@@ -27,9 +36,29 @@ type BlockJSON struct {
     BlockHash    string `json:"blockHash"`
     PreviousHash string `json:"previousHash"`
     TxCount      int    `json:"txCount"`
+    MerkleRoot   string `json:"merkleRoot,omitempty"`
+    Codec        string `json:"codec"`
 }
 
 var verboseFlag bool
+var storageOptions db.Options
+
+// chainFlags are the command-line overrides of per-chain settings from the
+// network config; the zero values (and -1 for HashVersion) mean "not set".
+type chainFlags struct {
+    ConfigPath     string
+    HashVersion    int
+    HashAlgorithm  string
+    ValidatorsPath string
+    ChainSpecPath  string
+    EnableRules    []string
+    DisableRules   []string
+    PolicyPath     string
+    ClockSkew      int64
+    MinTimestamp   int64
+    AllowEmpty     bool
+    Severity       string
+}
 
 func main() {
     // DAY 1 PDF-REQUIRED FLAGS [file:15]
@@ -45,18 +74,79 @@ func main() {
     dbPath := flag.String("db", "./leveldb-data", "Path to LevelDB database")
     db1Path := flag.String("db1", "./node1-data", "Path to first database")
     db2Path := flag.String("db2", "./node2-data", "Path to second database")
-    cmd := flag.String("cmd", "help", "Command: load, block, scan-errors, compare, consensus, watch, report")
+    cmd := flag.String("cmd", "help", "Command: load, keygen, block, proof, verify-proof, scan-errors, rules, keyspace, compare, consensus, watch, report")
     numBlocks := flag.Int("blocks", 10, "Number of blocks to load")
     showVersion := flag.Bool("version", false, "Show version")
     rpcURL := flag.String("rpc", "", "RPC endpoint URL")
+    rpc1URL := flag.String("rpc1", "", "RPC endpoint of first node for comparison")
+    rpc2URL := flag.String("rpc2", "", "RPC endpoint of second node for comparison")
     watchInterval := flag.Int("interval", 2, "Watch mode interval in seconds")
     configPath := flag.String("config", "nodes.json", "Path to network config file")
     reportPath := flag.String("report", "inspector-report.json", "Output path for report")
+    snapshot := flag.Bool("snapshot", false, "Copy LevelDB files to a temp dir before reading (for databases held by a running node)")
+    keyPrefix := flag.String("key-prefix", "", "Block key prefix (default block-)")
+    keyEncoding := flag.String("key-encoding", "", "Block key height encoding: decimal, padded, uint64be")
+    keyWidth := flag.Int("key-width", 0, "Digits in padded height keys (default 20)")
+    hashPrefix := flag.String("hash-prefix", "", "Key prefix of hash-keyed blocks; height keys then hold the block hash")
+    blockHash := flag.String("hash", "", "Block hash or hash prefix for the block command")
+    ancestors := flag.Int("ancestors", 0, "Walk N ancestors from the block")
+    descendants := flag.Int("descendants", 0, "Walk N levels of descendants from the block")
+    codecName := flag.String("codec", "", "Block value codec: json, cbor, binary, optionally gzip+/snappy+ wrapped (default: auto-detect; load writes json)")
+    hashVersion := flag.Int("hash-version", -1, "Block hash scheme for blocks without hash_version: 0 legacy, 1 length-prefixed (default: from --config, else 0)")
+    hashAlgo := flag.String("hash-algo", "", "Block hash algorithm: sha256, sha256d, sha3-256, blake2b-256 (default: from --config, else sha256)")
+    difficulty := flag.Int("difficulty", 0, "Mine loaded blocks to N leading zero bits (default: initial_difficulty of the pow section in --config)")
+    validatorsPath := flag.String("validators", "", "Validator set file; every block must then be signed by a listed validator (default: validators in --config)")
+    signKey := flag.String("sign-key", "", "Ed25519 key file: load signs blocks with it, keygen writes it")
+    txID := flag.String("tx", "", "Transaction ID or prefix for the proof command")
+    proofPath := flag.String("proof", "", "Proof file written by proof and read by verify-proof")
+    chainSpecPath := flag.String("chain-spec", "", "Chain spec file with genesis hash and checkpoints (default: chain_spec in --config)")
+    enableRules := flag.String("rules", "", "Comma-separated rule ids to scan with, replacing rules.enable in --config (default: all)")
+    disableRules := flag.String("disable-rules", "", "Comma-separated rule ids to leave out, on top of rules.disable in --config")
+    workers := flag.Int("workers", 1, "Decode and check blocks on N goroutines during scan-errors")
+    incremental := flag.Bool("incremental", false, "scan-errors resumes after the block verified by the last scan (see --checkpoint)")
+    checkpointPath := flag.String("checkpoint", "", "Scan checkpoint file scan-errors writes and --incremental reads (default: <db>.scan-checkpoint.json with --incremental)")
+    policyPath := flag.String("policy", "", "Scan policy file with thresholds and rule severities (default: policy in --config)")
+    clockSkew := flag.Int64("clock-skew", -1, "Seconds a timestamp may be ahead of the scan (default: from the policy, else 300)")
+    minTimestamp := flag.Int64("min-timestamp", 0, "Earliest allowed block timestamp (default: from the policy, else ten years ago)")
+    allowEmpty := flag.Bool("allow-empty", false, "Do not report blocks without data")
+    severity := flag.String("severity", "", "Comma-separated rule=severity overrides, e.g. empty_block=info")
+    baselinePath := flag.String("baseline", "", "Baseline file of accepted findings scan-errors suppresses")
+    writeBaseline := flag.String("write-baseline", "", "Write the findings of this scan-errors run to a baseline file")
+    fromHeight := flag.Int("from", 0, "scan-errors starts at this height (partial scan)")
+    toHeight := flag.Int("to", -1, "scan-errors stops at this height (default: tip; partial scan)")
+    sample := flag.Int("sample", 0, "scan-errors checks only N heights of the range (partial scan)")
+    sampleMode := flag.String("sample-mode", "random", "How --sample picks heights: random or stride")
+    seed := flag.Int64("seed", 0, "Seed for --sample-mode random (default: a fresh one, reported in the result)")
     
     flag.Parse()
 
     // DAY 1: LOGGING CONTROL [file:15]
     verboseFlag = *verbose
+    storageOptions = db.Options{
+        ReadOnly: true,
+        Snapshot: *snapshot,
+        KeySchema: db.KeySchema{
+            Prefix:     *keyPrefix,
+            Encoding:   *keyEncoding,
+            Width:      *keyWidth,
+            HashPrefix: *hashPrefix,
+        },
+        Codec: *codecName,
+    }
+    flags := chainFlags{
+        ConfigPath:     *configPath,
+        HashVersion:    *hashVersion,
+        HashAlgorithm:  *hashAlgo,
+        ValidatorsPath: *validatorsPath,
+        ChainSpecPath:  *chainSpecPath,
+        EnableRules:    splitList(*enableRules),
+        DisableRules:   splitList(*disableRules),
+        PolicyPath:     *policyPath,
+        ClockSkew:      *clockSkew,
+        MinTimestamp:   *minTimestamp,
+        AllowEmpty:     *allowEmpty,
+        Severity:       *severity,
+    }
     if *verbose {
         log.SetFlags(log.LstdFlags | log.Lshortfile)
         log.Println("✓ Verbose mode enabled")
@@ -71,31 +161,66 @@ func main() {
 
     // DAY 1: SHORTCUT ROUTES [file:15]
     if *path != "" && *height > 0 {
-        viewBlockDay1(*path, *rpcURL, *height, *jsonOutput)
+        viewBlockDay1(*path, *rpcURL, *height, flags, *jsonOutput)
         return
     }
     
     if *compare1 != "" && *compare2 != "" {
-        compareNodesDay1(*compare1, *compare2, *jsonOutput)
+        compareNodesDay1(*compare1, *compare2, flags, *jsonOutput)
         return
     }
 
     // EXISTING COMMANDS
     switch *cmd {
     case "load":
-        loadSampleData(*dbPath, *numBlocks)
+        loadSampleData(*dbPath, *numBlocks, flags, *signKey, *difficulty)
+    case "keygen":
+        runKeygen(*signKey)
     case "block":
-        viewBlock(*dbPath, *rpcURL, *jsonOutput)
+        byHeight := -1
+        flag.Visit(func(f *flag.Flag) {
+            if f.Name == "height" {
+                byHeight = *height
+            }
+        })
+        viewBlock(*dbPath, *rpcURL, *blockHash, byHeight, *ancestors, *descendants, flags, *jsonOutput)
+    case "proof":
+        runProof(*dbPath, *rpcURL, *txID, *proofPath, flags, *jsonOutput)
+    case "verify-proof":
+        runVerifyProof(*dbPath, *rpcURL, *proofPath, flags, *jsonOutput)
     case "scan-errors":
-        runScan(*dbPath, *jsonOutput)
+        opts := chainOptionsFor(*dbPath, *rpcURL, flags)
+        opts.Workers = *workers
+        opts.Incremental = *incremental
+        opts.Checkpoint = scanCheckpointPath(*checkpointPath, *dbPath, *rpcURL, *incremental)
+        if *fromHeight > 0 || *toHeight >= 0 || *sample > 0 {
+            if *incremental {
+                fmt.Println("❌ Error: --incremental cannot be combined with --from, --to or --sample")
+                os.Exit(1)
+            }
+            opts.Range = &errors.ScanRange{From: *fromHeight, To: *toHeight, Sample: *sample, SampleMode: *sampleMode, Seed: *seed}
+        }
+        if *baselinePath != "" {
+            baseline, err := errors.LoadBaseline(*baselinePath)
+            if err != nil {
+                fmt.Printf("❌ Error: %v\n", err)
+                os.Exit(1)
+            }
+            opts.Baseline, opts.BaselinePath = baseline, *baselinePath
+        }
+        runScan(*dbPath, *rpcURL, opts, flags, *writeBaseline, *jsonOutput)
+    case "rules":
+        runRules(chainOptionsFor(*dbPath, *rpcURL, flags), *jsonOutput)
+    case "keyspace":
+        runKeyspace(*dbPath, *rpcURL, flags, *jsonOutput)
     case "compare":
-        runCompare(*db1Path, *db2Path, *jsonOutput)
+        runCompare(*db1Path, *db2Path, *rpc1URL, *rpc2URL, flags, *jsonOutput)
     case "consensus":
-        runConsensus(*configPath, *jsonOutput)
+        runConsensus(flags, *jsonOutput)
     case "watch":
         runWatch(*rpcURL, *watchInterval)
     case "report":
-        runFullReport(*configPath, *reportPath)
+        runFullReport(flags, *reportPath)
     case "help":
         printUsage()
     default:
@@ -105,46 +230,37 @@ func main() {
 }
 
 // DAY 1: NEW FUNCTION [file:15]
-func viewBlockDay1(dbPath, rpcURL string, height int, jsonMode bool) {
-    var block *blocks.Block
-    var err error
-    
-    if rpcURL != "" {
-        if verboseFlag {
+func viewBlockDay1(dbPath, rpcURL string, height int, flags chainFlags, jsonMode bool) {
+    if verboseFlag {
+        if rpcURL != "" {
             log.Printf("Fetching block %d from RPC: %s", height, rpcURL)
-        }
-        client := rpc.NewClient(rpcURL)
-        block, err = client.FetchBlock(height)
-        if err != nil {
-            errors.FormatError("BLOCK_FETCH_FAILED", err.Error(), height)
-            return
-        }
-    } else {
-        if verboseFlag {
+        } else {
             log.Printf("Opening database: %s", dbPath)
         }
-        storage, err := db.NewStorage(dbPath)
-        if err != nil {
-            errors.FormatError("DB_OPEN_FAILED", err.Error(), height)
-            return
-        }
-        defer storage.Close()
-        
-        if verboseFlag {
-            log.Printf("Loading block at height: %d", height)
-        }
-        block, err = storage.LoadBlock(height)
-        if err != nil {
-            errors.FormatError("BLOCK_FETCH_FAILED", err.Error(), height)
-            return
-        }
+    }
+    source, storage, err := openChain(dbPath, rpcURL, flags)
+    if err != nil {
+        errors.FormatError("DB_OPEN_FAILED", err.Error(), height)
+        return
+    }
+    defer source.Close()
+    
+    if verboseFlag {
+        log.Printf("Loading block at height: %d", height)
+    }
+    block, codecName, err := loadBlockWithCodec(source, height, storage.Codec)
+    if err != nil {
+        errors.FormatError("BLOCK_FETCH_FAILED", err.Error(), height)
+        return
     }
 
     output := BlockJSON{
         Height:       block.Height,
         BlockHash:    block.Hash,
         PreviousHash: block.PrevHash,
-        TxCount:      1,
+        TxCount:      block.TxCount(),
+        MerkleRoot:   block.MerkleRoot,
+        Codec:        codecName,
     }
 
     if jsonMode {
@@ -155,16 +271,21 @@ func viewBlockDay1(dbPath, rpcURL string, height int, jsonMode bool) {
         fmt.Printf("\n=== Block %d ===\n", output.Height)
         fmt.Printf("Hash:      %s\n", output.BlockHash)
         fmt.Printf("PrevHash:  %s\n", output.PreviousHash)
-        fmt.Printf("TxCount:   %d\n\n", output.TxCount)
+        fmt.Printf("TxCount:   %d\n", output.TxCount)
+        if output.MerkleRoot != "" {
+            fmt.Printf("Merkle:    %s\n", output.MerkleRoot)
+        }
+        fmt.Printf("Codec:     %s\n\n", output.Codec)
     }
 }
 
 // DAY 1: NEW FUNCTION [file:15]
-func compareNodesDay1(path1, path2 string, jsonMode bool) {
+func compareNodesDay1(path1, path2 string, flags chainFlags, jsonMode bool) {
+    opts1, opts2 := chainOptionsFor(path1, "", flags), chainOptionsFor(path2, "", flags)
     if verboseFlag {
         log.Printf("Opening node 1: %s", path1)
     }
-    storage1, err := db.NewStorage(path1)
+    storage1, _, err := openChain(path1, "", flags)
     if err != nil {
         errors.FormatError("DB_OPEN_FAILED", fmt.Sprintf("Node1: %v", err), 0)
         return
@@ -174,7 +295,7 @@ func compareNodesDay1(path1, path2 string, jsonMode bool) {
     if verboseFlag {
         log.Printf("Opening node 2: %s", path2)
     }
-    storage2, err := db.NewStorage(path2)
+    storage2, _, err := openChain(path2, "", flags)
     if err != nil {
         errors.FormatError("DB_OPEN_FAILED", fmt.Sprintf("Node2: %v", err), 0)
         return
@@ -184,12 +305,47 @@ func compareNodesDay1(path1, path2 string, jsonMode bool) {
     if verboseFlag {
         log.Println("Starting node comparison...")
     }
-    result := errors.CompareNodes(storage1, storage2, path1, path2)
+    result := errors.CompareNodesWithOptions(storage1, storage2, path1, path2, opts1, opts2)
     errors.OutputComparisonResult(result, jsonMode)
 }
 
-func loadSampleData(dbPath string, numBlocks int) {
-    storage, err := db.NewStorage(dbPath)
+// maxLoadDifficulty keeps sample mining to seconds rather than hours.
+const maxLoadDifficulty = 24
+
+func loadSampleData(dbPath string, numBlocks int, flags chainFlags, signKey string, difficulty int) {
+    opts := chainOptionsFor(dbPath, "", flags)
+    pow := opts.Pow
+    if difficulty > 0 {
+        if pow == nil {
+            pow = &blocks.PowParams{}
+        }
+        params := *pow
+        params.InitialDifficulty = difficulty
+        pow = &params
+    }
+    if pow != nil {
+        if pow.InitialDifficulty > maxLoadDifficulty {
+            fmt.Printf("❌ Error: difficulty %d is above the load limit of %d\n", pow.InitialDifficulty, maxLoadDifficulty)
+            os.Exit(1)
+        }
+        // Only v2 hashes commit to the nonce.
+        if opts.HashVersion < blocks.HashV2 {
+            opts.HashVersion = blocks.HashV2
+        }
+    }
+
+    var priv ed25519.PrivateKey
+    if signKey != "" {
+        key, err := validators.LoadKey(signKey)
+        if err != nil {
+            fmt.Printf("❌ Error: %v\n", err)
+            os.Exit(1)
+        }
+        priv = key
+    }
+
+    write := storageFor(dbPath, "", flags)
+    storage, err := db.OpenStorage(dbPath, db.Options{KeySchema: write.KeySchema, Codec: write.Codec})
     if err != nil {
         fmt.Printf("❌ Error: %v\n", err)
         os.Exit(1)
@@ -199,17 +355,44 @@ func loadSampleData(dbPath string, numBlocks int) {
     fmt.Printf("Loading %d sample blocks into %s...\n", numBlocks, dbPath)
 
     prevHash := "0"
+    times := make([]int64, 0, numBlocks)
+    blockDifficulty := 0
     for i := 0; i < numBlocks; i++ {
         timestamp := time.Now().Unix() + int64(i*10)
+        times = append(times, timestamp)
         data := fmt.Sprintf("Transaction data for block %d", i)
-        hash := blocks.ComputeHash(i, prevHash, data, timestamp)
+        txs := sampleTransactions(i)
 
         block := &blocks.Block{
-            Height:    i,
-            Hash:      hash,
-            PrevHash:  prevHash,
-            Data:      data,
-            Timestamp: timestamp,
+            Height:       i,
+            PrevHash:     prevHash,
+            Data:         data,
+            Timestamp:    timestamp,
+            Transactions: txs,
+            MerkleRoot:   blocks.ComputeMerkleRoot(txs),
+        }
+        block.DeclareHashVersion(opts.HashVersion)
+        if pow != nil {
+            var start, end int64
+            if pow.IsRetarget(i) {
+                start, end = times[i-pow.RetargetWindow], times[i-1]
+            }
+            blockDifficulty = pow.ExpectedDifficulty(i, blockDifficulty, start, end)
+            block.Difficulty = blockDifficulty
+            if err := blocks.Mine(block, opts.HashVersion, opts.HashAlgorithm, 1<<32); err != nil {
+                fmt.Printf("❌ Error mining block %d: %v\n", i, err)
+                os.Exit(1)
+            }
+        } else {
+            hash, err := blocks.ComputeBlockHashWith(block, opts.HashVersion, opts.HashAlgorithm)
+            if err != nil {
+                fmt.Printf("❌ Error: %v\n", err)
+                os.Exit(1)
+            }
+            block.Hash = hash
+        }
+        if priv != nil {
+            blocks.SignBlock(block, priv)
         }
 
         if err := storage.SaveBlock(block); err != nil {
@@ -217,127 +400,611 @@ func loadSampleData(dbPath string, numBlocks int) {
             os.Exit(1)
         }
 
-        fmt.Printf("✔ Block %d stored\n", i)
-        prevHash = hash
+        if block.Difficulty > 0 {
+            fmt.Printf("✔ Block %d mined (difficulty %d, nonce %d) and stored\n", i, block.Difficulty, block.Nonce)
+        } else {
+            fmt.Printf("✔ Block %d stored\n", i)
+        }
+        prevHash = block.Hash
     }
 
     fmt.Println("\n✅ Data loading complete!")
 }
 
-func viewBlock(dbPath, rpcURL string, jsonMode bool) {
-    if flag.NArg() < 1 {
-        fmt.Println("Usage: inspector -cmd block <height> [--rpc URL] [--json]")
+// runKeygen writes a fresh signing key for load --sign-key and prints the
+// entry to add to a validator set file.
+func runKeygen(keyPath string) {
+    if keyPath == "" {
+        fmt.Println("Usage: inspector -cmd keygen --sign-key <file>")
+        os.Exit(1)
+    }
+    if _, err := os.Stat(keyPath); err == nil {
+        fmt.Printf("❌ Error: %s already exists\n", keyPath)
+        os.Exit(1)
+    }
+
+    key, err := validators.GenerateKey(keyPath)
+    if err != nil {
+        fmt.Printf("❌ Error: %v\n", err)
+        os.Exit(1)
+    }
+    fmt.Printf("✔ Key written to %s\n", keyPath)
+    fmt.Println("\nValidator set entry:")
+    entry, _ := json.MarshalIndent(validators.Validator{Name: strings.TrimSuffix(filepath.Base(keyPath), filepath.Ext(keyPath)), PublicKey: key.PublicKey}, "", "  ")
+    fmt.Println(string(entry))
+}
+
+var sampleAccounts = []string{"alice", "bob", "carol", "dave"}
+
+// sampleTransactions gives block height one to three transfers between the
+// sample accounts; nonces keep increasing across the chain.
+func sampleTransactions(height int) []blocks.Transaction {
+    txs := make([]blocks.Transaction, 1+height%3)
+    for j := range txs {
+        tx := blocks.Transaction{
+            Sender:   sampleAccounts[(height+j)%len(sampleAccounts)],
+            Receiver: sampleAccounts[(height+j+1)%len(sampleAccounts)],
+            Amount:   int64(10 * (j + 1)),
+            Nonce:    uint64(height*3 + j),
+        }
+        tx.ID = blocks.ComputeTxID(tx)
+        txs[j] = tx
+    }
+    return txs
+}
+
+// viewBlock shows the block at height, or with hash when that is set. A
+// height of -1 means neither flag was given, and the positional argument is
+// read as a height if it is all digits and a block is stored there,
+// otherwise as a hash or hash prefix.
+func viewBlock(dbPath, rpcURL, hash string, height, ancestors, descendants int, flags chainFlags, jsonMode bool) {
+    if (flag.NArg() < 1 && hash == "" && height < 0) || (hash != "" && height >= 0) {
+        fmt.Println("Usage: inspector -cmd block <height|hash> [--height N | --hash <hash|prefix>] [--ancestors N] [--descendants N] [--rpc URL] [--json]")
         os.Exit(1)
     }
     
-    height := 0
-    fmt.Sscanf(flag.Arg(0), "%d", &height)
-    
-    var block *blocks.Block
-    var err error
+    digits := ""
+    if hash == "" && height < 0 {
+        if n, err := strconv.Atoi(flag.Arg(0)); err == nil && n >= 0 {
+            height, digits = n, flag.Arg(0)
+        } else {
+            hash = flag.Arg(0)
+        }
+    }
     
     if rpcURL != "" {
-        fmt.Printf("Fetching block %d from RPC: %s\n", height, rpcURL)
-        client := rpc.NewClient(rpcURL)
-        block, err = client.FetchBlock(height)
+        fmt.Printf("Fetching block from RPC: %s\n", rpcURL)
+    }
+    source, storage, err := openChain(dbPath, rpcURL, flags)
+    if err != nil {
+        fmt.Printf("❌ Error opening source: %v\n", err)
+        os.Exit(1)
+    }
+    defer source.Close()
+    
+    if digits != "" {
+        if _, err := source.LoadBlockRaw(height); err == db.ErrBlockNotFound {
+            hash = digits
+        }
+    }
+    
+    var index *db.HashIndex
+    if hash != "" || ancestors > 0 || descendants > 0 {
+        index, err = db.IndexOf(source)
         if err != nil {
-            fmt.Printf("❌ Error fetching block via RPC: %v\n", err)
+            fmt.Printf("❌ Error indexing block hashes: %v\n", err)
             os.Exit(1)
         }
-    } else {
-        storage, err := db.NewStorage(dbPath)
-        if err != nil {
-            fmt.Printf("❌ Error opening database: %v\n", err)
+    }
+    if hash != "" {
+        entry, err := index.Resolve(hash)
+        if err != nil && digits != "" {
+            fmt.Printf("❌ Error: no block at height %s, and %v\n", digits, err)
             os.Exit(1)
         }
-        defer storage.Close()
-        
-        block, err = storage.LoadBlock(height)
         if err != nil {
-            fmt.Printf("❌ Error loading block: %v\n", err)
+            fmt.Printf("❌ Error: %v\n", err)
             os.Exit(1)
         }
+        height = entry.Height
+    }
+    
+    block, codecName, err := loadBlockWithCodec(source, height, storage.Codec)
+    if err != nil {
+        fmt.Printf("❌ Error loading block: %v\n", err)
+        os.Exit(1)
+    }
+    
+    var ancestorList, descendantList []db.IndexEntry
+    if index != nil {
+        ancestorList = index.Ancestors(block.Hash, ancestors)
+        descendantList = index.Descendants(block.Hash, descendants)
     }
     
     if jsonMode {
-        data, _ := json.MarshalIndent(block, "", "  ")
+        output := struct {
+            *blocks.Block
+            TxCount     int             `json:"tx_count"`
+            Codec       string          `json:"codec"`
+            Ancestors   []db.IndexEntry `json:"ancestors,omitempty"`
+            Descendants []db.IndexEntry `json:"descendants,omitempty"`
+        }{block, block.TxCount(), codecName, ancestorList, descendantList}
+        data, _ := json.MarshalIndent(output, "", "  ")
         fmt.Println(string(data))
     } else {
         fmt.Printf("\n=== Block %d ===\n", block.Height)
         fmt.Printf("Hash:      %s\n", block.Hash)
         fmt.Printf("PrevHash:  %s\n", block.PrevHash)
         fmt.Printf("Timestamp: %s (Unix: %d)\n", time.Unix(block.Timestamp, 0).UTC(), block.Timestamp)
-        fmt.Printf("Data:      %s\n\n", block.Data)
+        fmt.Printf("Data:      %s\n", block.Data)
+        fmt.Printf("TxCount:   %d\n", block.TxCount())
+        if block.Difficulty > 0 {
+            fmt.Printf("PoW:       difficulty %d, nonce %d\n", block.Difficulty, block.Nonce)
+        }
+        if block.Proposer != "" {
+            fmt.Printf("Proposer:  %s\n", block.Proposer)
+            fmt.Printf("Signature: %s\n", block.Signature)
+        }
+        if block.MerkleRoot != "" {
+            fmt.Printf("Merkle:    %s\n", block.MerkleRoot)
+        }
+        fmt.Printf("Codec:     %s\n\n", codecName)
+        printTransactions(block.Transactions)
+        printIndexEntries("Ancestors", ancestorList, ancestors)
+        printIndexEntries("Descendants", descendantList, descendants)
+    }
+}
+
+func printTransactions(txs []blocks.Transaction) {
+    if len(txs) == 0 {
+        return
     }
+    fmt.Printf("Transactions (%d):\n", len(txs))
+    for i, tx := range txs {
+        fmt.Printf("  [%d] %s  %s -> %s  amount=%d nonce=%d\n", i, tx.ID, tx.Sender, tx.Receiver, tx.Amount, tx.Nonce)
+    }
+    fmt.Println()
+}
+
+func printIndexEntries(title string, entries []db.IndexEntry, requested int) {
+    if requested <= 0 {
+        return
+    }
+    fmt.Printf("%s (%d):\n", title, len(entries))
+    for _, entry := range entries {
+        fmt.Printf("  #%-8d %s  (prev %s)\n", entry.Height, entry.Hash, entry.PrevHash)
+    }
+    fmt.Println()
 }
 
-func runScan(dbPath string, jsonMode bool) {
-    storage, err := db.NewStorage(dbPath)
+// loadBlockWithCodec decodes the raw value itself so the detected codec can
+// be shown. override is the codec openChain settled on, if any.
+func loadBlockWithCodec(source db.BlockSource, height int, override string) (*blocks.Block, string, error) {
+    raw, err := source.LoadBlockRaw(height)
     if err != nil {
+        return nil, "", err
+    }
+    return codec.Decode(raw, override)
+}
+
+// chainOptionsFor works out the chain settings of the chain read from dbPath
+// or rpcURL: flags win, then the matching node in the config file, then the
+// network defaults of that file, then the chain spec. A missing config file
+// is not an error.
+func chainOptionsFor(dbPath, rpcURL string, flags chainFlags) errors.ScanOptions {
+    var opts errors.ScanOptions
+    validatorsPath, specPath, policyPath := flags.ValidatorsPath, flags.ChainSpecPath, flags.PolicyPath
+    if _, err := os.Stat(flags.ConfigPath); err == nil {
+        cfg, err := config.LoadConfig(flags.ConfigPath)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "⚠️  Ignoring config %s: %v\n", flags.ConfigPath, err)
+        } else {
+            node := cfg.FindNode(dbPath, rpcURL)
+            opts.HashVersion = cfg.NodeHashVersion(node)
+            opts.HashAlgorithm = cfg.NodeHashAlgorithm(node)
+            opts.Pow = cfg.Pow
+            if validatorsPath == "" {
+                validatorsPath = cfg.Validators
+            }
+            if specPath == "" {
+                specPath = cfg.ChainSpec
+            }
+            if policyPath == "" {
+                policyPath = cfg.Policy
+            }
+            if cfg.Rules != nil {
+                opts.EnabledRules = cfg.Rules.Enable
+                opts.DisabledRules = cfg.Rules.Disable
+            }
+        }
+    }
+
+    if specPath != "" {
+        opts.Spec = loadChainSpec(specPath)
+        if opts.HashAlgorithm == "" {
+            opts.HashAlgorithm = opts.Spec.HashAlgorithm
+        }
+    }
+
+    hashVersion, hashAlgo := flags.HashVersion, flags.HashAlgorithm
+    if hashVersion >= 0 {
+        if !blocks.ValidHashVersion(hashVersion) {
+            fmt.Printf("❌ Error: unknown hash version %d\n", hashVersion)
+            os.Exit(1)
+        }
+        opts.HashVersion = hashVersion
+    }
+    if hashAlgo != "" {
+        if _, err := blocks.HashAlgorithm(hashAlgo); err != nil {
+            fmt.Printf("❌ Error: %v\n", err)
+            os.Exit(1)
+        }
+        opts.HashAlgorithm = hashAlgo
+    }
+    if validatorsPath != "" {
+        set, err := validators.LoadSet(validatorsPath)
+        if err != nil {
+            fmt.Printf("❌ Error: %v\n", err)
+            os.Exit(1)
+        }
+        opts.Validators = set
+    }
+    if len(flags.EnableRules) > 0 {
+        opts.EnabledRules = flags.EnableRules
+    }
+    opts.DisabledRules = append(opts.DisabledRules, flags.DisableRules...)
+    if _, err := rules.Select(opts.EnabledRules, opts.DisabledRules); err != nil {
         fmt.Printf("❌ Error: %v\n", err)
         os.Exit(1)
     }
-    defer storage.Close()
+    opts.Policy = scanPolicy(policyPath, flags)
+    return opts
+}
 
-    result := errors.ScanErrors(storage, dbPath)
+// scanPolicy loads the policy file, if any, and applies the flag overrides.
+func scanPolicy(path string, flags chainFlags) *rules.Policy {
+    policy := rules.DefaultPolicy()
+    if path != "" {
+        loaded, err := rules.LoadPolicy(path)
+        if err != nil {
+            fmt.Printf("❌ Error: %v\n", err)
+            os.Exit(1)
+        }
+        policy = loaded
+    }
+    if flags.ClockSkew >= 0 {
+        policy.ClockSkew = flags.ClockSkew
+    }
+    if flags.MinTimestamp != 0 {
+        policy.MinTimestamp = flags.MinTimestamp
+    }
+    if flags.AllowEmpty {
+        policy.AllowEmptyBlocks = true
+    }
+    for _, item := range splitList(flags.Severity) {
+        id, level, ok := strings.Cut(item, "=")
+        if !ok {
+            fmt.Printf("❌ Error: --severity wants rule=severity, got %q\n", item)
+            os.Exit(1)
+        }
+        if policy.Severity == nil {
+            policy.Severity = make(map[string]rules.Severity)
+        }
+        policy.Severity[strings.TrimSpace(id)] = rules.Severity(strings.TrimSpace(level))
+    }
+    if err := policy.Validate(); err != nil {
+        fmt.Printf("❌ Error: %v\n", err)
+        os.Exit(1)
+    }
+    return policy
+}
+
+// splitList parses a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+    var items []string
+    for _, item := range strings.Split(value, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            items = append(items, item)
+        }
+    }
+    return items
+}
+
+func loadChainSpec(path string) *chainspec.Spec {
+    spec, err := chainspec.Load(path)
+    if err != nil {
+        fmt.Printf("❌ Error: %v\n", err)
+        os.Exit(1)
+    }
+    return spec
+}
+
+// scanCheckpointPath is where scan-errors keeps its checkpoint: the given
+// file, or next to the database for incremental scans. Databases are opened
+// read-only, so the checkpoint never goes inside one.
+func scanCheckpointPath(path, dbPath, rpcURL string, incremental bool) string {
+    if path != "" || !incremental {
+        return path
+    }
+    if rpcURL != "" {
+        fmt.Println("❌ Error: --incremental over --rpc needs a --checkpoint file")
+        os.Exit(1)
+    }
+    return filepath.Clean(dbPath) + ".scan-checkpoint.json"
+}
+
+// runScan exits with status 1 when the scan fails or finds anything the
+// baseline does not accept.
+func runScan(dbPath, rpcURL string, opts errors.ScanOptions, flags chainFlags, baselineOut string, jsonMode bool) {
+    source, _, err := openChain(dbPath, rpcURL, flags)
+    if err != nil {
+        fmt.Printf("❌ Error: %v\n", err)
+        os.Exit(1)
+    }
+    defer source.Close()
+
+    name := dbPath
+    if rpcURL != "" {
+        name = rpcURL
+    }
+    result := errors.ScanErrorsWithOptions(source, name, opts)
     errors.OutputScanResult(result, jsonMode)
+    if baselineOut != "" && !strings.HasPrefix(result.Status, "ERROR:") {
+        baseline := errors.NewBaseline(result)
+        if err := errors.SaveBaseline(baselineOut, baseline); err != nil {
+            fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+            os.Exit(1)
+        }
+        fmt.Fprintf(os.Stderr, "✔ Baseline of %d finding(s) written to %s\n", len(baseline.Entries), baselineOut)
+    }
+    if result.TotalErrors > 0 || strings.HasPrefix(result.Status, "ERROR:") {
+        os.Exit(1)
+    }
+}
+
+func runKeyspace(dbPath, rpcURL string, flags chainFlags, jsonMode bool) {
+    source, _, err := openChain(dbPath, rpcURL, flags)
+    if err != nil {
+        fmt.Printf("❌ Error: %v\n", err)
+        os.Exit(1)
+    }
+    defer source.Close()
+
+    report, err := keyspace.Analyze(source)
+    if err != nil {
+        fmt.Printf("❌ Error: %v\n", err)
+        os.Exit(1)
+    }
+    keyspace.OutputReport(report, jsonMode)
+}
+
+// ruleInfo is one line of the rules listing.
+type ruleInfo struct {
+    ID          string         `json:"id"`
+    Severity    rules.Severity `json:"severity"`
+    Enabled     bool           `json:"enabled"`
+    Description string         `json:"description"`
 }
 
-func runCompare(db1Path, db2Path string, jsonMode bool) {
-    storage1, err := db.NewStorage(db1Path)
+func runRules(opts errors.ScanOptions, jsonMode bool) {
+    selected, _ := rules.Select(opts.EnabledRules, opts.DisabledRules)
+    enabled := make(map[string]bool)
+    for _, rule := range selected {
+        enabled[rule.ID()] = true
+    }
+
+    infos := []ruleInfo{}
+    for _, rule := range rules.All() {
+        infos = append(infos, ruleInfo{rule.ID(), opts.Policy.SeverityOf(rule), enabled[rule.ID()], rule.Description()})
+    }
+    if jsonMode {
+        jsonData, _ := json.MarshalIndent(infos, "", "  ")
+        fmt.Println(string(jsonData))
+        return
+    }
+
+    fmt.Printf("\n%-26s %-9s %s\n", "RULE", "SEVERITY", "DESCRIPTION")
+    for _, info := range infos {
+        mark := " "
+        if !info.Enabled {
+            mark = "-"
+        }
+        fmt.Printf("%s%-25s %-9s %s\n", mark, info.ID, info.Severity, info.Description)
+    }
+    fmt.Printf("\n%d of %d rules enabled (- marks disabled rules)\n", len(selected), len(infos))
+}
+
+func runCompare(db1Path, db2Path, rpc1, rpc2 string, flags chainFlags, jsonMode bool) {
+    opts1, opts2 := chainOptionsFor(db1Path, rpc1, flags), chainOptionsFor(db2Path, rpc2, flags)
+    source1, _, err := openChain(db1Path, rpc1, flags)
     if err != nil {
         fmt.Printf("❌ Error opening Node1: %v\n", err)
         os.Exit(1)
     }
-    defer storage1.Close()
+    defer source1.Close()
 
-    storage2, err := db.NewStorage(db2Path)
+    source2, _, err := openChain(db2Path, rpc2, flags)
     if err != nil {
         fmt.Printf("❌ Error opening Node2: %v\n", err)
         os.Exit(1)
     }
-    defer storage2.Close()
+    defer source2.Close()
 
-    result := errors.CompareNodes(storage1, storage2, db1Path, db2Path)
+    name1, name2 := db1Path, db2Path
+    if rpc1 != "" {
+        name1 = rpc1
+    }
+    if rpc2 != "" {
+        name2 = rpc2
+    }
+    result := errors.CompareNodesWithOptions(source1, source2, name1, name2, opts1, opts2)
     errors.OutputComparisonResult(result, jsonMode)
 }
 
-func runConsensus(configPath string, jsonMode bool) {
-    cfg, err := config.LoadConfig(configPath)
+// openSource picks the block source for a command: a live node when rpcURL
+// is set, a JSON block file when path names a regular file, and a LevelDB
+// directory otherwise. Callers pass read-only options; only load asks for
+// write access.
+func openSource(path, rpcURL string, opts db.Options) (db.BlockSource, error) {
+    if rpcURL != "" {
+        return rpc.NewClient(rpcURL), nil
+    }
+    if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+        return db.LoadFileSource(path)
+    }
+    return db.OpenStorage(path, opts)
+}
+
+// openChain opens the chain a command reads, with the options storageFor
+// settles on. They are returned for the codec override they carry.
+func openChain(path, rpcURL string, flags chainFlags) (db.BlockSource, db.Options, error) {
+    opts := storageFor(path, rpcURL, flags)
+    source, err := openSource(path, rpcURL, opts)
+    return source, opts, err
+}
+
+// storageFor is how the database at path is read: the command-line storage
+// options, with the key_schema and codec of the node the config file lists
+// for it. chainOptionsFor reports a config file that does not load.
+func storageFor(path, rpcURL string, flags chainFlags) db.Options {
+    if rpcURL != "" {
+        opts := storageOptions
+        opts.Codec = ""
+        return opts
+    }
+    if _, err := os.Stat(flags.ConfigPath); err != nil {
+        return storageOptions
+    }
+    cfg, err := config.LoadConfig(flags.ConfigPath)
+    if err != nil {
+        return storageOptions
+    }
+    return nodeStorage(cfg.FindNode(path, ""))
+}
+
+// nodeStorage is the command-line storage options with a configured node's
+// key_schema and codec, which win.
+func nodeStorage(node *config.NodeConfig) db.Options {
+    opts := storageOptions
+    if node == nil {
+        return opts
+    }
+    if node.KeySchema != nil {
+        opts.KeySchema = *node.KeySchema
+    }
+    if node.Codec != "" {
+        opts.Codec = node.Codec
+    }
+    return opts
+}
+
+func runConsensus(flags chainFlags, jsonMode bool) {
+    cfg, err := config.LoadConfig(flags.ConfigPath)
     if err != nil {
         fmt.Printf("❌ Error loading config: %v\n", err)
         os.Exit(1)
     }
 
+    nodes := openNodes(cfg, flags)
+    defer closeNodes(nodes)
+    attachChainSpec(cfg, nodes, flags)
+
+    for _, node := range nodes {
+        if node.Err != nil {
+            fmt.Printf("⚠️  Warning: Cannot open %s: %v\n", node.Name, node.Err)
+        }
+    }
+
+    result, err := consensus.AnalyzeConsensusWithModel(nodes, networkPolicy(cfg, flags).Health)
+    if err != nil {
+        fmt.Printf("❌ Error analyzing consensus: %v\n", err)
+        os.Exit(1)
+    }
+
+    consensus.OutputConsensusResult(result, jsonMode)
+}
+
+// networkPolicy is the scan policy for the whole network: --policy, else
+// policy in the config, with the flag overrides applied.
+func networkPolicy(cfg *config.NetworkConfig, flags chainFlags) *rules.Policy {
+    path := flags.PolicyPath
+    if path == "" {
+        path = cfg.Policy
+    }
+    return scanPolicy(path, flags)
+}
+
+// openNodes opens every configured node. A node's db_path wins over its
+// rpc_url; nodes that cannot be reached keep their error in NodeInfo.Err so
+// the analysis can report them.
+func openNodes(cfg *config.NetworkConfig, flags chainFlags) []consensus.NodeInfo {
     var nodes []consensus.NodeInfo
     for _, nodeConf := range cfg.Nodes {
-        storage, err := db.NewStorage(nodeConf.DBPath)
+        node := consensus.NodeInfo{
+            Name:          nodeConf.Name,
+            DBPath:        nodeConf.DBPath,
+            RPCURL:        nodeConf.RPCURL,
+            Height:        -1,
+            HashVersion:   cfg.NodeHashVersion(&nodeConf),
+            HashAlgorithm: cfg.NodeHashAlgorithm(&nodeConf),
+        }
+        if flags.HashVersion >= 0 {
+            node.HashVersion = flags.HashVersion
+        }
+        if flags.HashAlgorithm != "" {
+            node.HashAlgorithm = flags.HashAlgorithm
+        }
+
+        rpcURL := ""
+        if nodeConf.DBPath == "" {
+            rpcURL = nodeConf.RPCURL
+        }
+        source, err := openSource(nodeConf.DBPath, rpcURL, nodeStorage(&nodeConf))
         if err != nil {
-            fmt.Printf("⚠️  Warning: Cannot open %s: %v\n", nodeConf.Name, err)
+            node.Err = err
+            nodes = append(nodes, node)
             continue
         }
-        defer storage.Close()
 
-        nodes = append(nodes, consensus.NodeInfo{
-            Name:    nodeConf.Name,
-            DBPath:  nodeConf.DBPath,
-            Height:  storage.GetMaxHeight(),
-            Storage: storage,
-        })
+        tip, err := source.Tip()
+        if err != nil {
+            source.Close()
+            node.Err = err
+            nodes = append(nodes, node)
+            continue
+        }
+        node.Height = tip.Highest
+        node.Source = source
+        nodes = append(nodes, node)
     }
+    return nodes
+}
 
-    if len(nodes) == 0 {
-        fmt.Println("❌ Error: No valid nodes found")
-        os.Exit(1)
+// attachChainSpec gives every node the network's chain spec, --chain-spec
+// taking precedence over chain_spec in the config.
+func attachChainSpec(cfg *config.NetworkConfig, nodes []consensus.NodeInfo, flags chainFlags) {
+    path := flags.ChainSpecPath
+    if path == "" {
+        path = cfg.ChainSpec
     }
-
-    result, err := consensus.AnalyzeConsensus(nodes)
-    if err != nil {
-        fmt.Printf("❌ Error analyzing consensus: %v\n", err)
-        os.Exit(1)
+    if path == "" {
+        return
+    }
+    spec := loadChainSpec(path)
+    for i := range nodes {
+        nodes[i].Spec = spec
+        if nodes[i].HashAlgorithm == "" {
+            nodes[i].HashAlgorithm = spec.HashAlgorithm
+        }
     }
+}
 
-    consensus.OutputConsensusResult(result, jsonMode)
+func closeNodes(nodes []consensus.NodeInfo) {
+    for _, node := range nodes {
+        if node.Source != nil {
+            node.Source.Close()
+        }
+    }
 }
 
 func runWatch(rpcURL string, interval int) {
@@ -350,32 +1017,20 @@ func runWatch(rpcURL string, interval int) {
     watcher.Watch(rpcURL, interval)
 }
 
-func runFullReport(configPath, reportPath string) {
+func runFullReport(flags chainFlags, reportPath string) {
     fmt.Println("Generating comprehensive network report...")
     
-    cfg, err := config.LoadConfig(configPath)
+    cfg, err := config.LoadConfig(flags.ConfigPath)
     if err != nil {
         fmt.Printf("❌ Error loading config: %v\n", err)
         os.Exit(1)
     }
 
-    var nodes []consensus.NodeInfo
-    for _, nodeConf := range cfg.Nodes {
-        storage, err := db.NewStorage(nodeConf.DBPath)
-        if err != nil {
-            continue
-        }
-        defer storage.Close()
-
-        nodes = append(nodes, consensus.NodeInfo{
-            Name:    nodeConf.Name,
-            DBPath:  nodeConf.DBPath,
-            Height:  storage.GetMaxHeight(),
-            Storage: storage,
-        })
-    }
+    nodes := openNodes(cfg, flags)
+    defer closeNodes(nodes)
+    attachChainSpec(cfg, nodes, flags)
 
-    consensusResult, _ := consensus.AnalyzeConsensus(nodes)
+    consensusResult, _ := consensus.AnalyzeConsensusWithModel(nodes, networkPolicy(cfg, flags).Health)
 
     fullReport := &report.FullReport{
         Version:   version,
@@ -404,8 +1059,13 @@ func printUsage() {
     
     fmt.Println("\n📋 ORIGINAL COMMANDS:")
     fmt.Println("  load        Load sample blocks")
-    fmt.Println("  block       View specific block")
-    fmt.Println("  scan-errors Scan for errors")
+    fmt.Println("  block       View specific block by height, hash or hash prefix")
+    fmt.Println("  keygen      Write a test signing key (--sign-key file)")
+    fmt.Println("  proof       Merkle inclusion proof of a transaction (--tx ID <height>)")
+    fmt.Println("  verify-proof Check a proof file (--proof file) against the chain")
+    fmt.Println("  scan-errors Scan for errors (exit status 1 on new findings)")
+    fmt.Println("  rules       List the scan rules and whether they are enabled")
+    fmt.Println("  keyspace    Map every stored block: segments, orphans, side chains")
     fmt.Println("  compare     Compare two nodes")
     fmt.Println("  consensus   Consensus analysis")
     fmt.Println("  watch       Real-time monitoring")
@@ -419,5 +1079,35 @@ func printUsage() {
     fmt.Println("  --json       JSON output")
     fmt.Println("  --verbose    verbose mode")
     fmt.Println("  --quiet      quiet mode")
+    fmt.Println("  --snapshot   read a copy of the database (node may keep running)")
+    fmt.Println("  --key-prefix, --key-encoding, --key-width, --hash-prefix")
+    fmt.Println("               key layout of non-BHIV databases (key_schema in nodes.json)")
+    fmt.Println("  --hash       block hash or prefix (--height N for a height); --ancestors/--descendants N walk from it")
+    fmt.Println("  --codec      force a value codec (json, cbor, binary, gzip+json, ...)")
+    fmt.Println("  --hash-version  hash scheme of blocks without hash_version (0 legacy, 1 length-prefixed)")
+    fmt.Println("  --hash-algo  block hash algorithm (sha256, sha256d, sha3-256, blake2b-256)")
+    fmt.Println("  --difficulty mine loaded blocks to N leading zero bits (pow section in nodes.json)")
+    fmt.Println("  --chain-spec chain spec file (genesis, checkpoints); chain_spec in nodes.json")
+    fmt.Println("  --validators validator set file; blocks must be signed by its validators")
+    fmt.Println("  --rules      scan only these rule ids (comma-separated); rules.enable in nodes.json")
+    fmt.Println("  --disable-rules  leave these rule ids out; rules.disable in nodes.json")
+    fmt.Println("  --workers    scan-errors decodes and checks blocks on N goroutines")
+    fmt.Println("  --incremental  scan-errors only checks blocks added since the last scan")
+    fmt.Println("  --checkpoint file recording how far scan-errors verified (default <db>.scan-checkpoint.json)")
+    fmt.Println("  --policy     policy file (thresholds, timestamp statistics, rule severities,")
+    fmt.Println("               fail_on severity, health weights and grades)")
+    fmt.Println("               for scan-errors and consensus; policy in nodes.json")
+    fmt.Println("  --clock-skew, --min-timestamp, --allow-empty, --severity rule=level,...")
+    fmt.Println("               override the scan policy")
+    fmt.Println("  --baseline   suppress the accepted findings in this file; only new ones fail the scan")
+    fmt.Println("  --write-baseline  write this scan's findings to a baseline file")
+    fmt.Println("  --from, --to scan-errors checks only this height range (partial coverage)")
+    fmt.Println("  --sample     scan-errors checks N heights; --sample-mode random|stride, --seed")
+    fmt.Println("  --sign-key   key file that load signs blocks with (created by keygen)")
+    fmt.Println("  --tx         transaction ID or prefix for proof")
+    fmt.Println("  --proof      proof file to write (proof) or check (verify-proof)")
+    fmt.Println("  --rpc        read from a live node instead of --db")
+    fmt.Println("  --rpc1/2     live nodes for compare instead of --db1/--db2")
+    fmt.Println("\n  --db, --db1, --db2 and db_path accept a LevelDB directory or a JSON block file")
     fmt.Println()
 }
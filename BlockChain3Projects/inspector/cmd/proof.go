@@ -0,0 +1,186 @@
+package main
+
+import (
+    "encoding/json"
+    "flag"
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+
+    "inspector/internal/blocks"
+)
+
+// runProof builds the inclusion proof of one transaction in the block at the
+// given height and prints it, or writes it to proofPath for later checking.
+func runProof(dbPath, rpcURL, txID, proofPath string, flags chainFlags, jsonMode bool) {
+    if flag.NArg() < 1 || txID == "" {
+        fmt.Println("Usage: inspector -cmd proof --tx <id|prefix> [--proof out.json] [--rpc URL] [--json] <height>")
+        os.Exit(1)
+    }
+    height, err := strconv.Atoi(flag.Arg(0))
+    if err != nil {
+        fmt.Printf("❌ Error: invalid height %q\n", flag.Arg(0))
+        os.Exit(1)
+    }
+
+    source, storage, err := openChain(dbPath, rpcURL, flags)
+    if err != nil {
+        fmt.Printf("❌ Error opening source: %v\n", err)
+        os.Exit(1)
+    }
+    defer source.Close()
+
+    block, _, err := loadBlockWithCodec(source, height, storage.Codec)
+    if err != nil {
+        fmt.Printf("❌ Error loading block: %v\n", err)
+        os.Exit(1)
+    }
+    if len(block.Transactions) == 0 {
+        fmt.Printf("❌ Error: block %d has no transaction list\n", height)
+        os.Exit(1)
+    }
+
+    index, err := findTransaction(block, txID)
+    if err != nil {
+        fmt.Printf("❌ Error: %v\n", err)
+        os.Exit(1)
+    }
+
+    tree := blocks.NewMerkleTree(block.Transactions)
+    if tree.Root() != block.MerkleRoot {
+        fmt.Printf("⚠️  Block %d stores merkle root %s but its transactions hash to %s\n", height, block.MerkleRoot, tree.Root())
+    }
+    proof, err := tree.Proof(index)
+    if err != nil {
+        fmt.Printf("❌ Error: %v\n", err)
+        os.Exit(1)
+    }
+    proof.Height = block.Height
+    proof.BlockHash = block.Hash
+
+    data, _ := json.MarshalIndent(proof, "", "  ")
+    if proofPath != "" {
+        if err := os.WriteFile(proofPath, append(data, '\n'), 0644); err != nil {
+            fmt.Printf("❌ Error writing proof: %v\n", err)
+            os.Exit(1)
+        }
+        fmt.Printf("✔ Proof for transaction %s written to %s\n", proof.TxID, proofPath)
+        return
+    }
+    if jsonMode {
+        fmt.Println(string(data))
+        return
+    }
+
+    fmt.Printf("\n=== Merkle Proof: Block %d, Transaction %d ===\n", proof.Height, proof.Index)
+    fmt.Printf("Block:     %s\n", proof.BlockHash)
+    fmt.Printf("TxID:      %s\n", proof.TxID)
+    fmt.Printf("Root:      %s\n", proof.Root)
+    fmt.Printf("Steps (%d):\n", len(proof.Steps))
+    for i, step := range proof.Steps {
+        side := "right"
+        if step.Left {
+            side = "left "
+        }
+        fmt.Printf("  [%d] %s %s\n", i, side, step.Hash)
+    }
+    fmt.Println()
+}
+
+// findTransaction resolves a full transaction ID or a unique prefix of one.
+func findTransaction(block *blocks.Block, txID string) (int, error) {
+    txID = strings.ToLower(txID)
+    if i := block.FindTransaction(txID); i >= 0 {
+        return i, nil
+    }
+
+    match := -1
+    for i, tx := range block.Transactions {
+        if strings.HasPrefix(tx.ID, txID) {
+            if match >= 0 {
+                return 0, fmt.Errorf("transaction prefix %s is ambiguous in block %d", txID, block.Height)
+            }
+            match = i
+        }
+    }
+    if match < 0 {
+        return 0, fmt.Errorf("transaction %s not found in block %d", txID, block.Height)
+    }
+    return match, nil
+}
+
+type proofCheck struct {
+    Proof        *blocks.MerkleProof `json:"proof"`
+    Valid        bool                `json:"valid"`
+    Error        string              `json:"error,omitempty"`
+    ChainChecked bool                `json:"chain_checked"`
+    ChainError   string              `json:"chain_error,omitempty"`
+}
+
+// runVerifyProof checks a proof file on its own and, when the chain can be
+// opened, that the block at the proof's height still carries the same hash
+// and merkle root.
+func runVerifyProof(dbPath, rpcURL, proofPath string, flags chainFlags, jsonMode bool) {
+    if proofPath == "" {
+        fmt.Println("Usage: inspector -cmd verify-proof --proof <file> [--db path | --rpc URL] [--json]")
+        os.Exit(1)
+    }
+    data, err := os.ReadFile(proofPath)
+    if err != nil {
+        fmt.Printf("❌ Error reading proof: %v\n", err)
+        os.Exit(1)
+    }
+    var proof blocks.MerkleProof
+    if err := json.Unmarshal(data, &proof); err != nil {
+        fmt.Printf("❌ Error parsing proof: %v\n", err)
+        os.Exit(1)
+    }
+
+    check := proofCheck{Proof: &proof}
+    if err := blocks.VerifyProof(&proof); err != nil {
+        check.Error = err.Error()
+    } else {
+        check.Valid = true
+    }
+
+    if source, storage, err := openChain(dbPath, rpcURL, flags); err != nil {
+        check.ChainError = fmt.Sprintf("chain not checked: %v", err)
+    } else {
+        defer source.Close()
+        check.ChainChecked = true
+        block, _, err := loadBlockWithCodec(source, proof.Height, storage.Codec)
+        switch {
+        case err != nil:
+            check.ChainError = fmt.Sprintf("block %d: %v", proof.Height, err)
+        case block.Hash != proof.BlockHash:
+            check.ChainError = fmt.Sprintf("block %d has hash %s, proof names %s", proof.Height, block.Hash, proof.BlockHash)
+        case block.MerkleRoot != proof.Root:
+            check.ChainError = fmt.Sprintf("block %d has merkle root %s, proof names %s", proof.Height, block.MerkleRoot, proof.Root)
+        }
+    }
+
+    if jsonMode {
+        out, _ := json.MarshalIndent(check, "", "  ")
+        fmt.Println(string(out))
+    } else {
+        fmt.Printf("\n=== Proof Check: Block %d, Transaction %s ===\n", proof.Height, proof.TxID)
+        if check.Valid {
+            fmt.Printf("✅ Proof leads to merkle root %s\n", proof.Root)
+        } else {
+            fmt.Printf("❌ Proof invalid: %s\n", check.Error)
+        }
+        if check.ChainChecked && check.ChainError == "" {
+            fmt.Printf("✅ Block %d on chain matches the proof\n", proof.Height)
+        } else if check.ChainChecked {
+            fmt.Printf("❌ %s\n", check.ChainError)
+        } else {
+            fmt.Printf("⚠️  %s\n", check.ChainError)
+        }
+        fmt.Println()
+    }
+
+    if !check.Valid || (check.ChainChecked && check.ChainError != "") {
+        os.Exit(1)
+    }
+}
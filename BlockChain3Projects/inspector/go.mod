@@ -2,6 +2,14 @@ module inspector
 
 go 1.25.4
 
-require github.com/syndtr/goleveldb v1.0.0
+require (
+	github.com/fxamacker/cbor/v2 v2.9.4
+	github.com/golang/snappy v0.0.0-20180518054509-2e65f85255db
+	github.com/syndtr/goleveldb v1.0.0
+	golang.org/x/crypto v0.46.0
+)
 
-require github.com/golang/snappy v0.0.0-20180518054509-2e65f85255db // indirect
+require (
+	github.com/x448/float16 v0.8.4 // indirect
+	golang.org/x/sys v0.39.0 // indirect
+)
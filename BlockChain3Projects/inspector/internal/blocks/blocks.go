@@ -1,9 +1,40 @@
 package blocks
 
+import (
+    "strings"
+)
+
 type Block struct {
-    Height    int    `json:"height"`
-    Hash      string `json:"hash"`
-    PrevHash  string `json:"prev_hash"`
-    Data      string `json:"data"`
-    Timestamp int64  `json:"timestamp"`
+    Height       int           `json:"height"`
+    Hash         string        `json:"hash"`
+    PrevHash     string        `json:"prev_hash"`
+    Data         string        `json:"data"`
+    Timestamp    int64         `json:"timestamp"`
+    Transactions []Transaction `json:"transactions,omitempty"`
+    MerkleRoot   string        `json:"merkle_root,omitempty"`
+    HashVersion  *int          `json:"hash_version,omitempty"`
+    Proposer     string        `json:"proposer,omitempty"`
+    Signature    string        `json:"signature,omitempty"`
+    Nonce        uint64        `json:"nonce,omitempty"`
+    Difficulty   int           `json:"difficulty,omitempty"`
+}
+
+type Transaction struct {
+    ID       string `json:"id"`
+    Sender   string `json:"sender"`
+    Receiver string `json:"receiver"`
+    Amount   int64  `json:"amount"`
+    Nonce    uint64 `json:"nonce"`
+}
+
+// TxCount is the number of transactions in the block. Legacy blocks carry a
+// single opaque Data payload and count as one transaction unless empty.
+func (b *Block) TxCount() int {
+    if len(b.Transactions) > 0 {
+        return len(b.Transactions)
+    }
+    if strings.TrimSpace(b.Data) != "" {
+        return 1
+    }
+    return 0
 }
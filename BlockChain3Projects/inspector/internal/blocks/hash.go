@@ -2,10 +2,31 @@ package blocks
 
 import (
     "crypto/sha256"
+    "encoding/binary"
     "encoding/hex"
+    "fmt"
     "strconv"
 )
 
+// Block hash versions. V0 is the original scheme and concatenates fields
+// without separators, so distinct blocks can share a preimage; V1 hashes a
+// length-prefixed encoding and also commits to the Merkle root; V2 adds the
+// proof-of-work nonce and difficulty.
+const (
+    HashV0 = 0
+    HashV1 = 1
+    HashV2 = 2
+
+    LatestHashVersion = HashV2
+)
+
+// v1Domain and v2Domain start every V1 and V2 preimage so block hashes
+// cannot be confused with other hashes built from the same fields.
+const (
+    v1Domain = "bhiv-block-v1"
+    v2Domain = "bhiv-block-v2"
+)
+
 func ComputeHash(height int, prevHash string, data string, timestamp int64) string {
     record := strconv.Itoa(height) + prevHash + data + strconv.FormatInt(timestamp, 10)
     h := sha256.New()
@@ -13,3 +34,63 @@ func ComputeHash(height int, prevHash string, data string, timestamp int64) stri
     hashed := h.Sum(nil)
     return hex.EncodeToString(hashed)
 }
+
+// ValidHashVersion reports whether version is a known hash scheme.
+func ValidHashVersion(version int) bool {
+    return version >= HashV0 && version <= LatestHashVersion
+}
+
+// EffectiveHashVersion is the scheme the block declares, falling back to the
+// chain default for blocks without a hash_version field. A declared 0 is
+// kept, so v0 blocks can live on a chain whose default is newer.
+func (b *Block) EffectiveHashVersion(chainDefault int) int {
+    if b.HashVersion != nil {
+        return *b.HashVersion
+    }
+    return chainDefault
+}
+
+// DeclareHashVersion records version in the block's hash_version field.
+func (b *Block) DeclareHashVersion(version int) {
+    b.HashVersion = &version
+}
+
+// ComputeBlockHash hashes block under the given scheme version with the
+// default algorithm.
+func ComputeBlockHash(b *Block, version int) (string, error) {
+    return ComputeBlockHashWith(b, version, DefaultHashAlgorithm)
+}
+
+// ComputeBlockHashWith hashes block under the given scheme version with the
+// named algorithm from the registry.
+func ComputeBlockHashWith(b *Block, version int, algorithm string) (string, error) {
+    newHash, err := HashAlgorithm(algorithm)
+    if err != nil {
+        return "", err
+    }
+
+    h := newHash()
+    switch version {
+    case HashV0:
+        h.Write([]byte(strconv.Itoa(b.Height) + b.PrevHash + b.Data + strconv.FormatInt(b.Timestamp, 10)))
+    case HashV1:
+        writeField(h, []byte(v1Domain))
+        writeCanonicalFields(h, b)
+    case HashV2:
+        writeField(h, []byte(v2Domain))
+        writeCanonicalFields(h, b)
+        writeField(h, binary.BigEndian.AppendUint64(nil, b.Nonce))
+        writeField(h, binary.BigEndian.AppendUint64(nil, uint64(b.Difficulty)))
+    default:
+        return "", fmt.Errorf("unknown hash version %d", version)
+    }
+    return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func writeCanonicalFields(h interface{ Write([]byte) (int, error) }, b *Block) {
+    writeField(h, binary.BigEndian.AppendUint64(nil, uint64(b.Height)))
+    writeField(h, []byte(b.PrevHash))
+    writeField(h, []byte(b.Data))
+    writeField(h, binary.BigEndian.AppendUint64(nil, uint64(b.Timestamp)))
+    writeField(h, []byte(b.MerkleRoot))
+}
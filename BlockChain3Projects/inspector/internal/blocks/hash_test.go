@@ -1,6 +1,8 @@
 package blocks
 
 import (
+    "encoding/json"
+    "strings"
     "testing"
 )
 
@@ -43,3 +45,47 @@ func TestHashDeterminism(t *testing.T) {
         }
     }
 }
+
+func TestDeclaredV0OverridesChainDefault(t *testing.T) {
+    var declared, undeclared Block
+    json.Unmarshal([]byte(`{"height": 1, "hash_version": 0}`), &declared)
+    json.Unmarshal([]byte(`{"height": 1}`), &undeclared)
+
+    if v := declared.EffectiveHashVersion(HashV1); v != HashV0 {
+        t.Errorf("Expected a declared v0 to win over the v1 default, got v%d", v)
+    }
+    if v := undeclared.EffectiveHashVersion(HashV1); v != HashV1 {
+        t.Errorf("Expected the v1 default without hash_version, got v%d", v)
+    }
+
+    data, _ := json.Marshal(&declared)
+    if !strings.Contains(string(data), `"hash_version":0`) {
+        t.Errorf("Expected a declared v0 to be written back, got %s", data)
+    }
+}
+
+func TestHashV1SeparatesFields(t *testing.T) {
+    a := &Block{Height: 1, PrevHash: "", Data: "2x", Timestamp: 1000}
+    b := &Block{Height: 12, PrevHash: "", Data: "x", Timestamp: 1000}
+
+    v0a, _ := ComputeBlockHash(a, HashV0)
+    v0b, _ := ComputeBlockHash(b, HashV0)
+    if v0a != v0b {
+        t.Fatal("Expected the legacy scheme to collide on these blocks")
+    }
+
+    v1a, _ := ComputeBlockHash(a, HashV1)
+    v1b, _ := ComputeBlockHash(b, HashV1)
+    if v1a == v1b {
+        t.Error("V1 hashes should differ when field boundaries differ")
+    }
+
+    a.MerkleRoot = "root"
+    if v1, _ := ComputeBlockHash(a, HashV1); v1 == v1a {
+        t.Error("V1 hash should commit to the merkle root")
+    }
+
+    if _, err := ComputeBlockHash(a, 99); err == nil {
+        t.Error("Expected unknown hash version to fail")
+    }
+}
@@ -0,0 +1,66 @@
+package blocks
+
+import (
+    "crypto/sha256"
+    "crypto/sha3"
+    "fmt"
+    "hash"
+    "sort"
+
+    "golang.org/x/crypto/blake2b"
+)
+
+// DefaultHashAlgorithm is the block hash algorithm of BHIV chains.
+const DefaultHashAlgorithm = "sha256"
+
+var hashAlgorithms = map[string]func() hash.Hash{}
+
+func init() {
+    RegisterHashAlgorithm("sha256", sha256.New)
+    RegisterHashAlgorithm("sha256d", func() hash.Hash { return &doubleSHA256{sha256.New()} })
+    RegisterHashAlgorithm("sha3-256", func() hash.Hash { return sha3.New256() })
+    RegisterHashAlgorithm("blake2b-256", func() hash.Hash {
+        h, _ := blake2b.New256(nil)
+        return h
+    })
+}
+
+// RegisterHashAlgorithm makes a block hash algorithm available by name,
+// replacing any earlier one of the same name.
+func RegisterHashAlgorithm(name string, newHash func() hash.Hash) {
+    hashAlgorithms[name] = newHash
+}
+
+// HashAlgorithm returns the constructor of the named algorithm; "" selects
+// DefaultHashAlgorithm.
+func HashAlgorithm(name string) (func() hash.Hash, error) {
+    if name == "" {
+        name = DefaultHashAlgorithm
+    }
+    newHash, ok := hashAlgorithms[name]
+    if !ok {
+        return nil, fmt.Errorf("unknown hash algorithm %q (known: %v)", name, HashAlgorithms())
+    }
+    return newHash, nil
+}
+
+// HashAlgorithms lists the registered algorithm names.
+func HashAlgorithms() []string {
+    names := make([]string, 0, len(hashAlgorithms))
+    for name := range hashAlgorithms {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// doubleSHA256 is SHA-256 applied to a SHA-256 digest, as used by Bitcoin.
+type doubleSHA256 struct {
+    hash.Hash
+}
+
+func (d *doubleSHA256) Sum(b []byte) []byte {
+    inner := d.Hash.Sum(nil)
+    outer := sha256.Sum256(inner)
+    return append(b, outer[:]...)
+}
@@ -0,0 +1,50 @@
+package blocks
+
+import (
+    "encoding/hex"
+    "testing"
+)
+
+func TestHashAlgorithmVectors(t *testing.T) {
+    vectors := map[string]string{
+        "sha256":      "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+        "sha256d":     "5df6e0e2761359d30a8275058e299fcc0381534545f55cf43e41983f5d4c9456",
+        "sha3-256":    "a7ffc6f8bf1ed76651c14756a061d662f580ff4de43b49fa82d80a4b80f8434a",
+        "blake2b-256": "0e5751c026e543b2e8ab2eb06099daa1d1e5df47778f7787faab45cdf12fe3a8",
+    }
+
+    for name, want := range vectors {
+        newHash, err := HashAlgorithm(name)
+        if err != nil {
+            t.Fatalf("%s: %v", name, err)
+        }
+        if got := hex.EncodeToString(newHash().Sum(nil)); got != want {
+            t.Errorf("%s of empty input: expected %s, got %s", name, want, got)
+        }
+    }
+
+    if _, err := HashAlgorithm("md5"); err == nil {
+        t.Error("Expected unknown algorithm to fail")
+    }
+}
+
+func TestBlockHashDependsOnAlgorithm(t *testing.T) {
+    block := &Block{Height: 3, PrevHash: "p", Data: "d", Timestamp: 9}
+
+    legacy, _ := ComputeBlockHashWith(block, HashV0, "")
+    if legacy != ComputeHash(3, "p", "d", 9) {
+        t.Error("Default algorithm should match the legacy ComputeHash")
+    }
+
+    seen := map[string]string{}
+    for _, name := range HashAlgorithms() {
+        hash, err := ComputeBlockHashWith(block, HashV1, name)
+        if err != nil {
+            t.Fatalf("%s: %v", name, err)
+        }
+        if other, dup := seen[hash]; dup {
+            t.Errorf("%s and %s produced the same block hash", name, other)
+        }
+        seen[hash] = name
+    }
+}
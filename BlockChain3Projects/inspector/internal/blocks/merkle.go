@@ -0,0 +1,183 @@
+package blocks
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+)
+
+// Leaves and inner nodes are hashed with different prefixes so a leaf can
+// never be passed off as an inner node.
+const (
+    merkleLeafPrefix = 0x00
+    merkleNodePrefix = 0x01
+)
+
+// MerkleTree keeps every level of the tree, leaves first, so proofs can be
+// read off without rehashing.
+type MerkleTree struct {
+    levels [][][]byte
+    txIDs  []string
+}
+
+// ProofStep is one sibling on the path from a leaf to the root. Left is set
+// when the sibling sits to the left of the running hash.
+type ProofStep struct {
+    Hash string `json:"hash"`
+    Left bool   `json:"left"`
+}
+
+// MerkleProof shows that TxID is the Index-th transaction under Root.
+// Height and BlockHash name the block the proof was taken from.
+type MerkleProof struct {
+    Height    int         `json:"height"`
+    BlockHash string      `json:"block_hash"`
+    TxID      string      `json:"tx_id"`
+    Index     int         `json:"index"`
+    Root      string      `json:"merkle_root"`
+    Steps     []ProofStep `json:"steps"`
+}
+
+// NewMerkleTree builds the tree over the transaction IDs. An odd node at any
+// level is paired with itself.
+func NewMerkleTree(txs []Transaction) *MerkleTree {
+    tree := &MerkleTree{}
+    if len(txs) == 0 {
+        return tree
+    }
+
+    level := make([][]byte, len(txs))
+    for i, tx := range txs {
+        level[i] = merkleLeaf(tx.ID)
+        tree.txIDs = append(tree.txIDs, tx.ID)
+    }
+    tree.levels = append(tree.levels, level)
+    for len(level) > 1 {
+        level = merkleLevel(level)
+        tree.levels = append(tree.levels, level)
+    }
+    return tree
+}
+
+// Root returns the hex root, or "" for a tree without transactions.
+func (t *MerkleTree) Root() string {
+    if len(t.levels) == 0 {
+        return ""
+    }
+    return hex.EncodeToString(t.levels[len(t.levels)-1][0])
+}
+
+// Proof returns the inclusion proof of the index-th transaction.
+func (t *MerkleTree) Proof(index int) (*MerkleProof, error) {
+    if index < 0 || index >= len(t.txIDs) {
+        return nil, fmt.Errorf("transaction index %d out of range (%d transactions)", index, len(t.txIDs))
+    }
+
+    proof := &MerkleProof{TxID: t.txIDs[index], Index: index, Root: t.Root()}
+    pos := index
+    for _, level := range t.levels[:len(t.levels)-1] {
+        sibling := pos ^ 1
+        if sibling >= len(level) {
+            sibling = pos
+        }
+        proof.Steps = append(proof.Steps, ProofStep{
+            Hash: hex.EncodeToString(level[sibling]),
+            Left: sibling < pos,
+        })
+        pos /= 2
+    }
+    return proof, nil
+}
+
+// VerifyProof recomputes the root from the proof's transaction ID and steps
+// and reports whether it matches the proof's root.
+func VerifyProof(proof *MerkleProof) error {
+    if proof.Root == "" {
+        return fmt.Errorf("proof has no merkle root")
+    }
+    want, err := hex.DecodeString(proof.Root)
+    if err != nil {
+        return fmt.Errorf("bad merkle root: %v", err)
+    }
+
+    if proof.Index < 0 {
+        return fmt.Errorf("negative transaction index %d", proof.Index)
+    }
+
+    current := merkleLeaf(proof.TxID)
+    pos := proof.Index
+    for i, step := range proof.Steps {
+        sibling, err := hex.DecodeString(step.Hash)
+        if err != nil {
+            return fmt.Errorf("step %d: bad hash: %v", i, err)
+        }
+        // Left must agree with the index, otherwise one proof could be
+        // replayed for a different position.
+        if step.Left != (pos%2 == 1) {
+            return fmt.Errorf("step %d: sibling side does not match index %d", i, proof.Index)
+        }
+        if step.Left {
+            current = merkleNode(sibling, current)
+        } else {
+            current = merkleNode(current, sibling)
+        }
+        pos /= 2
+    }
+    // Bits of the index above the proof's depth would otherwise be ignored,
+    // letting the proof claim any index that shares its low bits.
+    if pos != 0 {
+        return fmt.Errorf("index %d is out of range for a proof of %d steps", proof.Index, len(proof.Steps))
+    }
+
+    if !bytes.Equal(current, want) {
+        return fmt.Errorf("proof does not lead to merkle root %s", proof.Root)
+    }
+    return nil
+}
+
+// ComputeMerkleRoot returns the hex Merkle root over the transaction IDs,
+// or "" when there are no transactions.
+func ComputeMerkleRoot(txs []Transaction) string {
+    return NewMerkleTree(txs).Root()
+}
+
+// FindTransaction returns the index of the transaction whose ID is id, or -1.
+func (b *Block) FindTransaction(id string) int {
+    for i, tx := range b.Transactions {
+        if tx.ID == id {
+            return i
+        }
+    }
+    return -1
+}
+
+func merkleLeaf(txID string) []byte {
+    id, err := hex.DecodeString(txID)
+    if err != nil {
+        id = []byte(txID)
+    }
+    sum := sha256.Sum256(append([]byte{merkleLeafPrefix}, id...))
+    return sum[:]
+}
+
+func merkleNode(left, right []byte) []byte {
+    buf := make([]byte, 0, 1+len(left)+len(right))
+    buf = append(buf, merkleNodePrefix)
+    buf = append(buf, left...)
+    buf = append(buf, right...)
+    sum := sha256.Sum256(buf)
+    return sum[:]
+}
+
+func merkleLevel(level [][]byte) [][]byte {
+    next := make([][]byte, 0, (len(level)+1)/2)
+    for i := 0; i < len(level); i += 2 {
+        right := level[i]
+        if i+1 < len(level) {
+            right = level[i+1]
+        }
+        next = append(next, merkleNode(level[i], right))
+    }
+    return next
+}
@@ -0,0 +1,115 @@
+package blocks
+
+import (
+    "encoding/json"
+    "testing"
+)
+
+func sampleTxs(n int) []Transaction {
+    txs := make([]Transaction, n)
+    for i := range txs {
+        txs[i] = Transaction{Sender: "alice", Receiver: "bob", Amount: int64(i + 1), Nonce: uint64(i)}
+        txs[i].ID = ComputeTxID(txs[i])
+    }
+    return txs
+}
+
+func TestTxIDDependsOnFields(t *testing.T) {
+    a := Transaction{Sender: "ab", Receiver: "c", Amount: 1}
+    b := Transaction{Sender: "a", Receiver: "bc", Amount: 1}
+
+    if ComputeTxID(a) == ComputeTxID(b) {
+        t.Error("Shifting bytes between fields should change the transaction ID")
+    }
+}
+
+func TestMerkleRoot(t *testing.T) {
+    if root := ComputeMerkleRoot(nil); root != "" {
+        t.Errorf("Expected empty root for no transactions, got %s", root)
+    }
+
+    txs := sampleTxs(3)
+    root := ComputeMerkleRoot(txs)
+    if len(root) != 64 {
+        t.Fatalf("Expected 64 hex chars, got %d", len(root))
+    }
+    if root == ComputeMerkleRoot(txs[:2]) {
+        t.Error("Dropping a transaction should change the root")
+    }
+
+    txs[0], txs[1] = txs[1], txs[0]
+    if root == ComputeMerkleRoot(txs) {
+        t.Error("Reordering transactions should change the root")
+    }
+}
+
+func TestMerkleProofs(t *testing.T) {
+    for _, n := range []int{1, 2, 5, 8} {
+        txs := sampleTxs(n)
+        tree := NewMerkleTree(txs)
+        for i := range txs {
+            proof, err := tree.Proof(i)
+            if err != nil {
+                t.Fatalf("n=%d: proof %d failed: %v", n, i, err)
+            }
+            if err := VerifyProof(proof); err != nil {
+                t.Errorf("n=%d: proof %d did not verify: %v", n, i, err)
+            }
+        }
+    }
+
+    tree := NewMerkleTree(sampleTxs(5))
+    if _, err := tree.Proof(5); err == nil {
+        t.Error("Expected out of range index to fail")
+    }
+
+    proof, _ := tree.Proof(2)
+    proof.TxID = sampleTxs(5)[3].ID
+    if err := VerifyProof(proof); err == nil {
+        t.Error("Proof should not verify for a different transaction")
+    }
+
+    proof, _ = tree.Proof(2)
+    proof.Index = 3
+    if err := VerifyProof(proof); err == nil {
+        t.Error("Proof should not verify at a different index")
+    }
+
+    proof, _ = tree.Proof(2)
+    proof.Index = 2 + 1<<len(proof.Steps)
+    if err := VerifyProof(proof); err == nil {
+        t.Error("Proof should not verify at an index beyond its depth")
+    }
+    proof.Index = -2
+    if err := VerifyProof(proof); err == nil {
+        t.Error("Proof should not verify at a negative index")
+    }
+}
+
+func TestTxCount(t *testing.T) {
+    legacy := Block{Data: "transaction data"}
+    if legacy.TxCount() != 1 {
+        t.Errorf("Expected legacy block to count 1 transaction, got %d", legacy.TxCount())
+    }
+
+    empty := Block{}
+    if empty.TxCount() != 0 {
+        t.Errorf("Expected empty block to count 0 transactions, got %d", empty.TxCount())
+    }
+
+    block := Block{Data: "x", Transactions: sampleTxs(4)}
+    if block.TxCount() != 4 {
+        t.Errorf("Expected 4 transactions, got %d", block.TxCount())
+    }
+}
+
+func TestLegacyBlockDecodes(t *testing.T) {
+    var block Block
+    raw := `{"height":1,"hash":"h","prev_hash":"p","data":"legacy","timestamp":5}`
+    if err := json.Unmarshal([]byte(raw), &block); err != nil {
+        t.Fatalf("Failed to decode legacy block: %v", err)
+    }
+    if block.Transactions != nil || block.MerkleRoot != "" {
+        t.Errorf("Legacy block should have no transactions: %+v", block)
+    }
+}
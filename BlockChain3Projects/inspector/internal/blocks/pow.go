@@ -0,0 +1,135 @@
+package blocks
+
+import (
+    "encoding/hex"
+    "fmt"
+    "math"
+    "math/big"
+    "math/bits"
+)
+
+// PowParams are the proof-of-work rules of a chain. Difficulty is counted in
+// leading zero bits of the block hash. Every RetargetWindow blocks the
+// difficulty moves by log2(expected/actual window time), clamped to
+// MaxAdjust bits and never below MinDifficulty; in between it stays put.
+type PowParams struct {
+    InitialDifficulty int   `json:"initial_difficulty"`
+    MinDifficulty     int   `json:"min_difficulty"`
+    RetargetWindow    int   `json:"retarget_window"`
+    TargetSpacing     int64 `json:"target_spacing"`
+    MaxAdjust         int   `json:"max_adjust"`
+}
+
+// Validate rejects parameters the retarget rule cannot work with.
+func (p *PowParams) Validate() error {
+    if p.RetargetWindow < 0 || p.TargetSpacing < 0 || p.MaxAdjust < 0 {
+        return fmt.Errorf("pow: retarget_window, target_spacing and max_adjust must not be negative")
+    }
+    if p.RetargetWindow > 0 && p.TargetSpacing == 0 {
+        return fmt.Errorf("pow: retarget_window needs a target_spacing")
+    }
+    if p.InitialDifficulty < p.MinDifficulty {
+        return fmt.Errorf("pow: initial_difficulty is below min_difficulty")
+    }
+    return nil
+}
+
+// IsRetarget reports whether the difficulty may change at height.
+func (p *PowParams) IsRetarget(height int) bool {
+    return p.RetargetWindow > 0 && height > 0 && height%p.RetargetWindow == 0
+}
+
+// ExpectedDifficulty is the difficulty the block at height must declare.
+// prev is the previous block's difficulty; windowStart and windowEnd are the
+// timestamps of the first and last block of the window just closed, and are
+// only read at retarget heights.
+func (p *PowParams) ExpectedDifficulty(height, prev int, windowStart, windowEnd int64) int {
+    if height == 0 {
+        return p.InitialDifficulty
+    }
+    if !p.IsRetarget(height) {
+        return prev
+    }
+
+    actual := windowEnd - windowStart
+    if actual < 1 {
+        actual = 1
+    }
+    target := p.TargetSpacing * int64(p.RetargetWindow-1)
+    if target < 1 {
+        target = 1
+    }
+    adjust := int(math.Round(math.Log2(float64(target) / float64(actual))))
+    if p.MaxAdjust > 0 {
+        adjust = max(-p.MaxAdjust, min(p.MaxAdjust, adjust))
+    }
+    return max(p.MinDifficulty, prev+adjust)
+}
+
+// LeadingZeroBits counts the leading zero bits of a hex hash, or returns -1
+// when hash is not hex.
+func LeadingZeroBits(hash string) int {
+    raw, err := hex.DecodeString(hash)
+    if err != nil {
+        return -1
+    }
+    n := 0
+    for _, b := range raw {
+        if b != 0 {
+            return n + bits.LeadingZeros8(b)
+        }
+        n += 8
+    }
+    return n
+}
+
+// MeetsDifficulty reports whether hash has at least difficulty leading zero
+// bits.
+func MeetsDifficulty(hash string, difficulty int) bool {
+    return difficulty <= 0 || LeadingZeroBits(hash) >= difficulty
+}
+
+// Work is the expected number of hashes behind a block of the given
+// difficulty, 2^difficulty; blocks without proof of work count as 1.
+func Work(difficulty int) *big.Int {
+    if difficulty < 0 {
+        difficulty = 0
+    }
+    return new(big.Int).Lsh(big.NewInt(1), uint(difficulty))
+}
+
+// ProvenWork is the work a block actually proves: Work(b.Difficulty) when its
+// hash verifies under the scheme it is read with and meets the difficulty,
+// nothing otherwise. Schemes before v2 leave the nonce and difficulty out of
+// the hash, so blocks hashed with them prove no work either.
+func ProvenWork(b *Block, chainDefault int, algorithm string) *big.Int {
+    version := b.EffectiveHashVersion(chainDefault)
+    if version < HashV2 || !MeetsDifficulty(b.Hash, b.Difficulty) {
+        return new(big.Int)
+    }
+    if hash, err := ComputeBlockHashWith(b, version, algorithm); err != nil || hash != b.Hash {
+        return new(big.Int)
+    }
+    return Work(b.Difficulty)
+}
+
+// Mine searches nonces from b.Nonce upward until the block hash under the
+// given scheme meets b.Difficulty, and sets b.Hash. It gives up after
+// maxTries attempts.
+func Mine(b *Block, version int, algorithm string, maxTries uint64) error {
+    if version < HashV2 {
+        return fmt.Errorf("hash version %d does not commit to the nonce; mining needs v%d", version, HashV2)
+    }
+    for i := uint64(0); i < maxTries; i++ {
+        hash, err := ComputeBlockHashWith(b, version, algorithm)
+        if err != nil {
+            return err
+        }
+        if MeetsDifficulty(hash, b.Difficulty) {
+            b.Hash = hash
+            return nil
+        }
+        b.Nonce++
+    }
+    return fmt.Errorf("no nonce found for difficulty %d in %d tries", b.Difficulty, maxTries)
+}
@@ -0,0 +1,89 @@
+package blocks
+
+import (
+    "testing"
+)
+
+func TestLeadingZeroBits(t *testing.T) {
+    cases := map[string]int{
+        "ff":     0,
+        "7f":     1,
+        "0f":     4,
+        "00ff":   8,
+        "0001":   15,
+        "0000":   16,
+        "nothex": -1,
+    }
+    for hash, want := range cases {
+        if got := LeadingZeroBits(hash); got != want {
+            t.Errorf("LeadingZeroBits(%s): expected %d, got %d", hash, want, got)
+        }
+    }
+}
+
+func TestExpectedDifficulty(t *testing.T) {
+    p := &PowParams{InitialDifficulty: 4, MinDifficulty: 2, RetargetWindow: 5, TargetSpacing: 10, MaxAdjust: 2}
+
+    if got := p.ExpectedDifficulty(0, 0, 0, 0); got != 4 {
+        t.Errorf("Expected initial difficulty 4, got %d", got)
+    }
+    if got := p.ExpectedDifficulty(3, 6, 0, 0); got != 6 {
+        t.Errorf("Expected difficulty to hold between retargets, got %d", got)
+    }
+    // Window of 5 blocks should span 40s.
+    if got := p.ExpectedDifficulty(5, 4, 100, 140); got != 4 {
+        t.Errorf("Expected on-target window to keep difficulty, got %d", got)
+    }
+    if got := p.ExpectedDifficulty(5, 4, 100, 120); got != 5 {
+        t.Errorf("Expected twice-as-fast window to add a bit, got %d", got)
+    }
+    if got := p.ExpectedDifficulty(5, 4, 100, 101); got != 6 {
+        t.Errorf("Expected adjustment clamped to 2 bits, got %d", got)
+    }
+    if got := p.ExpectedDifficulty(5, 3, 100, 1000); got != 2 {
+        t.Errorf("Expected difficulty floored at 2, got %d", got)
+    }
+}
+
+func TestMine(t *testing.T) {
+    block := &Block{Height: 1, PrevHash: "p", Data: "d", Timestamp: 5, Difficulty: 8}
+
+    if err := Mine(block, HashV1, "", 1000); err == nil {
+        t.Error("Expected mining under v1 to be refused")
+    }
+    if err := Mine(block, HashV2, "", 1<<20); err != nil {
+        t.Fatalf("Mining failed: %v", err)
+    }
+    if !MeetsDifficulty(block.Hash, 8) {
+        t.Errorf("Mined hash %s does not meet difficulty 8", block.Hash)
+    }
+    if hash, _ := ComputeBlockHash(block, HashV2); hash != block.Hash {
+        t.Error("Mined hash does not match the block")
+    }
+    if Work(8).Int64() != 256 {
+        t.Errorf("Expected work 256 for difficulty 8, got %s", Work(8))
+    }
+}
+
+func TestProvenWork(t *testing.T) {
+    block := &Block{Height: 1, PrevHash: "p", Data: "d", Timestamp: 5, Difficulty: 6}
+    Mine(block, HashV2, "", 1<<20)
+
+    if work := ProvenWork(block, HashV2, ""); work.Int64() != 64 {
+        t.Errorf("Expected a mined v2 block to prove 64, got %s", work)
+    }
+    if work := ProvenWork(block, HashV1, ""); work.Sign() != 0 {
+        t.Errorf("Expected no work under v1, got %s", work)
+    }
+
+    forged := *block
+    forged.Difficulty = 60
+    if work := ProvenWork(&forged, HashV2, ""); work.Sign() != 0 {
+        t.Errorf("Expected a raised difficulty without a matching hash to prove nothing, got %s", work)
+    }
+    forged = *block
+    forged.Hash = "000000000000000000000000000000000000000000000000000000000000000f"
+    if work := ProvenWork(&forged, HashV2, ""); work.Sign() != 0 {
+        t.Errorf("Expected a hash that does not verify to prove nothing, got %s", work)
+    }
+}
@@ -0,0 +1,24 @@
+package blocks
+
+import (
+    "crypto/sha256"
+    "encoding/binary"
+    "encoding/hex"
+)
+
+// ComputeTxID hashes a transaction's fields, each length-prefixed so that no
+// two different transactions share a preimage. The ID field itself is not
+// part of the preimage.
+func ComputeTxID(tx Transaction) string {
+    h := sha256.New()
+    writeField(h, []byte(tx.Sender))
+    writeField(h, []byte(tx.Receiver))
+    writeField(h, binary.BigEndian.AppendUint64(nil, uint64(tx.Amount)))
+    writeField(h, binary.BigEndian.AppendUint64(nil, tx.Nonce))
+    return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeField(h interface{ Write([]byte) (int, error) }, field []byte) {
+    h.Write(binary.BigEndian.AppendUint64(nil, uint64(len(field))))
+    h.Write(field)
+}
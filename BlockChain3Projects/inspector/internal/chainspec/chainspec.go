@@ -0,0 +1,199 @@
+package chainspec
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "sort"
+    "strings"
+
+    "inspector/internal/blocks"
+    "inspector/internal/db"
+)
+
+// Spec pins down which chain a database must hold: its genesis block, hash
+// algorithm and trusted checkpoints.
+type Spec struct {
+    ChainID         string       `json:"chain_id"`
+    GenesisHash     string       `json:"genesis_hash"`
+    GenesisPrevHash string       `json:"genesis_prev_hash,omitempty"`
+    HashAlgorithm   string       `json:"hash_algorithm,omitempty"`
+    BlockInterval   int64        `json:"block_interval,omitempty"`
+    Checkpoints     []Checkpoint `json:"checkpoints,omitempty"`
+}
+
+// Checkpoint is a block hash trusted at a given height.
+type Checkpoint struct {
+    Height int    `json:"height"`
+    Hash   string `json:"hash"`
+}
+
+// Mismatch is one way a chain disagrees with its spec. Kind is "genesis" or
+// "checkpoint"; Actual is "" when the block is missing.
+type Mismatch struct {
+    Kind     string `json:"kind"`
+    Height   int    `json:"height"`
+    Expected string `json:"expected"`
+    Actual   string `json:"actual"`
+}
+
+// String describes the mismatch without its height, which callers print
+// in their own format.
+func (m Mismatch) String() string {
+    if m.Actual == "" {
+        return fmt.Sprintf("%s block missing (expected %s)", m.Kind, m.Expected)
+    }
+    return fmt.Sprintf("%s hash %s, expected %s", m.Kind, m.Actual, m.Expected)
+}
+
+func Load(path string) (*Spec, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read chain spec: %w", err)
+    }
+
+    var spec Spec
+    if err := json.Unmarshal(data, &spec); err != nil {
+        return nil, fmt.Errorf("failed to parse chain spec: %w", err)
+    }
+    if err := spec.validate(); err != nil {
+        return nil, fmt.Errorf("chain spec %s: %w", path, err)
+    }
+    return &spec, nil
+}
+
+func (s *Spec) validate() error {
+    if s.ChainID == "" {
+        return fmt.Errorf("chain_id is required")
+    }
+    if s.GenesisHash == "" {
+        return fmt.Errorf("genesis_hash is required")
+    }
+    if s.HashAlgorithm != "" {
+        if _, err := blocks.HashAlgorithm(s.HashAlgorithm); err != nil {
+            return err
+        }
+    }
+    if s.BlockInterval < 0 {
+        return fmt.Errorf("block_interval must not be negative")
+    }
+
+    s.GenesisHash = strings.ToLower(s.GenesisHash)
+    seen := make(map[int]bool)
+    for i := range s.Checkpoints {
+        cp := &s.Checkpoints[i]
+        if cp.Height < 0 || cp.Hash == "" {
+            return fmt.Errorf("checkpoint %d needs a height and hash", i)
+        }
+        if seen[cp.Height] {
+            return fmt.Errorf("duplicate checkpoint at height %d", cp.Height)
+        }
+        seen[cp.Height] = true
+        cp.Hash = strings.ToLower(cp.Hash)
+    }
+    sort.Slice(s.Checkpoints, func(i, j int) bool { return s.Checkpoints[i].Height < s.Checkpoints[j].Height })
+    return nil
+}
+
+// GenesisPrev is the PrevHash the genesis block must carry, "0" by default.
+func (s *Spec) GenesisPrev() string {
+    if s == nil || s.GenesisPrevHash == "" {
+        return "0"
+    }
+    return s.GenesisPrevHash
+}
+
+// CheckBlock compares a block read at height against the genesis hash and
+// any checkpoint at that height.
+func (s *Spec) CheckBlock(height int, hash string) []Mismatch {
+    var mismatches []Mismatch
+    hash = strings.ToLower(hash)
+    if height == 0 && hash != s.GenesisHash {
+        mismatches = append(mismatches, Mismatch{Kind: "genesis", Height: 0, Expected: s.GenesisHash, Actual: hash})
+    }
+    for _, cp := range s.Checkpoints {
+        if cp.Height == height && cp.Hash != hash {
+            mismatches = append(mismatches, Mismatch{Kind: "checkpoint", Height: height, Expected: cp.Hash, Actual: hash})
+        }
+    }
+    return mismatches
+}
+
+// CheckHashes holds a chain with the given tip against the genesis hash and
+// every checkpoint at or below the tip; checkpoints beyond the tip are not
+// yet reachable and are skipped. hashAt returns the hash stored at a height,
+// or false when there is none.
+func (s *Spec) CheckHashes(tip int, hashAt func(height int) (string, bool)) []Mismatch {
+    var mismatches []Mismatch
+    for _, height := range s.Heights() {
+        if height > tip {
+            continue
+        }
+        if hash, ok := hashAt(height); ok {
+            mismatches = append(mismatches, s.CheckBlock(height, hash)...)
+        } else {
+            mismatches = append(mismatches, s.Missing(height)...)
+        }
+    }
+    return mismatches
+}
+
+// Check runs CheckHashes against blocks read from source. A block that
+// fails to load for any reason other than being absent aborts the check.
+func (s *Spec) Check(source db.BlockSource) ([]Mismatch, error) {
+    tip, err := source.Tip()
+    if err != nil {
+        return nil, err
+    }
+
+    var loadErr error
+    mismatches := s.CheckHashes(tip.Highest, func(height int) (string, bool) {
+        block, err := source.LoadBlock(height)
+        if err != nil {
+            if !errors.Is(err, db.ErrBlockNotFound) && loadErr == nil {
+                loadErr = fmt.Errorf("block %d: %w", height, err)
+            }
+            return "", false
+        }
+        return block.Hash, true
+    })
+    return mismatches, loadErr
+}
+
+// Heights lists the heights the spec pins: the genesis block, then each
+// checkpoint above it.
+func (s *Spec) Heights() []int {
+    heights := []int{0}
+    for _, cp := range s.Checkpoints {
+        if cp.Height > 0 {
+            heights = append(heights, cp.Height)
+        }
+    }
+    return heights
+}
+
+// Missing reports the spec entries at height for a block that is absent.
+func (s *Spec) Missing(height int) []Mismatch {
+    var mismatches []Mismatch
+    if height == 0 {
+        mismatches = append(mismatches, Mismatch{Kind: "genesis", Height: 0, Expected: s.GenesisHash})
+    }
+    for _, cp := range s.Checkpoints {
+        if cp.Height == height {
+            mismatches = append(mismatches, Mismatch{Kind: "checkpoint", Height: height, Expected: cp.Hash})
+        }
+    }
+    return mismatches
+}
+
+// WrongChain reports whether mismatches include the genesis block, meaning
+// the database belongs to a different chain rather than a fork of this one.
+func WrongChain(mismatches []Mismatch) bool {
+    for _, m := range mismatches {
+        if m.Kind == "genesis" {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,77 @@
+package chainspec
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+
+    "inspector/internal/blocks"
+    "inspector/internal/db"
+)
+
+func TestLoadValidates(t *testing.T) {
+    dir := t.TempDir()
+    write := func(body string) string {
+        path := filepath.Join(dir, "spec.json")
+        os.WriteFile(path, []byte(body), 0644)
+        return path
+    }
+
+    spec, err := Load(write(`{"chain_id":"test","genesis_hash":"AB","checkpoints":[{"height":9,"hash":"CD"},{"height":3,"hash":"EF"}]}`))
+    if err != nil {
+        t.Fatalf("Failed to load spec: %v", err)
+    }
+    if spec.GenesisHash != "ab" || spec.Checkpoints[0].Height != 3 {
+        t.Errorf("Expected lowercased hashes and sorted checkpoints, got %+v", spec)
+    }
+    if spec.GenesisPrev() != "0" {
+        t.Errorf("Expected default genesis prev hash 0, got %s", spec.GenesisPrev())
+    }
+
+    bad := []string{
+        `{"genesis_hash":"ab"}`,
+        `{"chain_id":"test"}`,
+        `{"chain_id":"test","genesis_hash":"ab","hash_algorithm":"md5"}`,
+        `{"chain_id":"test","genesis_hash":"ab","checkpoints":[{"height":1,"hash":"a"},{"height":1,"hash":"b"}]}`,
+    }
+    for _, body := range bad {
+        if _, err := Load(write(body)); err == nil {
+            t.Errorf("Expected spec to be rejected: %s", body)
+        }
+    }
+}
+
+func TestCheck(t *testing.T) {
+    chain := []*blocks.Block{
+        {Height: 0, Hash: "g0"},
+        {Height: 1, Hash: "h1"},
+        {Height: 3, Hash: "h3"},
+    }
+    source, _ := db.NewMemorySource(chain)
+
+    spec := &Spec{ChainID: "test", GenesisHash: "g0", Checkpoints: []Checkpoint{
+        {Height: 1, Hash: "h1"},
+        {Height: 2, Hash: "h2"},
+        {Height: 3, Hash: "xx"},
+        {Height: 10, Hash: "h10"},
+    }}
+    mismatches, err := spec.Check(source)
+    if err != nil {
+        t.Fatalf("Check failed: %v", err)
+    }
+    if len(mismatches) != 2 {
+        t.Fatalf("Expected missing block 2 and wrong block 3, got %v", mismatches)
+    }
+    if mismatches[0].Height != 2 || mismatches[0].Actual != "" || mismatches[1].Height != 3 {
+        t.Errorf("Unexpected mismatches: %v", mismatches)
+    }
+    if WrongChain(mismatches) {
+        t.Error("Checkpoint mismatches alone should not mean a different chain")
+    }
+
+    spec.GenesisHash = "other"
+    mismatches, _ = spec.Check(source)
+    if !WrongChain(mismatches) {
+        t.Error("Expected a genesis mismatch to mean a different chain")
+    }
+}
@@ -0,0 +1,251 @@
+package codec
+
+import (
+    "encoding/binary"
+    "fmt"
+
+    "inspector/internal/blocks"
+)
+
+// binaryMagic starts every value in the binary format; the last byte is the
+// format version.
+var binaryMagic = []byte{'B', 'H', 'B', 1}
+
+// Field numbers of the binary format. After the magic header, each field is
+// a protobuf-style key (field<<3 | wire type) followed by a varint or a
+// length-prefixed byte string. Unknown fields are skipped, so newer writers
+// stay readable.
+const (
+    fieldHeight    = 1
+    fieldHash      = 2
+    fieldPrevHash  = 3
+    fieldData      = 4
+    fieldTimestamp = 5
+    fieldTx        = 6
+    fieldMerkle    = 7
+    fieldHashVer   = 8
+    fieldProposer  = 9
+    fieldSignature = 10
+    fieldNonce     = 11
+    fieldDiff      = 12
+)
+
+// Field numbers inside an embedded transaction (field 6, repeated).
+const (
+    txFieldID       = 1
+    txFieldSender   = 2
+    txFieldReceiver = 3
+    txFieldAmount   = 4
+    txFieldNonce    = 5
+)
+
+const (
+    wireVarint = 0
+    wireBytes  = 2
+)
+
+type binaryCodec struct{}
+
+func (binaryCodec) Name() string { return "binary" }
+
+func (binaryCodec) Encode(block *blocks.Block) ([]byte, error) {
+    out := append([]byte{}, binaryMagic...)
+    out = appendVarintField(out, fieldHeight, uint64(block.Height))
+    out = appendBytesField(out, fieldHash, []byte(block.Hash))
+    out = appendBytesField(out, fieldPrevHash, []byte(block.PrevHash))
+    out = appendBytesField(out, fieldData, []byte(block.Data))
+    out = appendVarintField(out, fieldTimestamp, zigzag(block.Timestamp))
+    for _, tx := range block.Transactions {
+        out = appendBytesField(out, fieldTx, encodeTx(tx))
+    }
+    if block.MerkleRoot != "" {
+        out = appendBytesField(out, fieldMerkle, []byte(block.MerkleRoot))
+    }
+    if block.HashVersion != nil {
+        out = appendVarintField(out, fieldHashVer, uint64(*block.HashVersion))
+    }
+    if block.Nonce != 0 {
+        out = appendVarintField(out, fieldNonce, block.Nonce)
+    }
+    if block.Difficulty != 0 {
+        out = appendVarintField(out, fieldDiff, uint64(block.Difficulty))
+    }
+    if block.Proposer != "" {
+        out = appendBytesField(out, fieldProposer, []byte(block.Proposer))
+    }
+    if block.Signature != "" {
+        out = appendBytesField(out, fieldSignature, []byte(block.Signature))
+    }
+    return out, nil
+}
+
+func encodeTx(tx blocks.Transaction) []byte {
+    var out []byte
+    out = appendBytesField(out, txFieldID, []byte(tx.ID))
+    out = appendBytesField(out, txFieldSender, []byte(tx.Sender))
+    out = appendBytesField(out, txFieldReceiver, []byte(tx.Receiver))
+    out = appendVarintField(out, txFieldAmount, zigzag(tx.Amount))
+    out = appendVarintField(out, txFieldNonce, tx.Nonce)
+    return out
+}
+
+func decodeTx(data []byte) (blocks.Transaction, error) {
+    r := &fieldReader{data: data}
+
+    var tx blocks.Transaction
+    for !r.done() {
+        field, wire, err := r.key()
+        if err != nil {
+            return tx, err
+        }
+        switch wire {
+        case wireVarint:
+            v, err := r.varint()
+            if err != nil {
+                return tx, err
+            }
+            switch field {
+            case txFieldAmount:
+                tx.Amount = unzigzag(v)
+            case txFieldNonce:
+                tx.Nonce = v
+            }
+        case wireBytes:
+            b, err := r.bytes()
+            if err != nil {
+                return tx, err
+            }
+            switch field {
+            case txFieldID:
+                tx.ID = string(b)
+            case txFieldSender:
+                tx.Sender = string(b)
+            case txFieldReceiver:
+                tx.Receiver = string(b)
+            }
+        default:
+            return tx, fmt.Errorf("transaction field %d: unsupported wire type %d", field, wire)
+        }
+    }
+    return tx, nil
+}
+
+func (binaryCodec) Decode(data []byte) (*blocks.Block, error) {
+    if len(data) < len(binaryMagic) || string(data[:len(binaryMagic)]) != string(binaryMagic) {
+        return nil, fmt.Errorf("missing binary header")
+    }
+    r := &fieldReader{data: data[len(binaryMagic):]}
+
+    var block blocks.Block
+    for !r.done() {
+        field, wire, err := r.key()
+        if err != nil {
+            return nil, err
+        }
+        switch wire {
+        case wireVarint:
+            v, err := r.varint()
+            if err != nil {
+                return nil, err
+            }
+            switch field {
+            case fieldHeight:
+                block.Height = int(v)
+            case fieldTimestamp:
+                block.Timestamp = unzigzag(v)
+            case fieldHashVer:
+                block.DeclareHashVersion(int(v))
+            case fieldNonce:
+                block.Nonce = v
+            case fieldDiff:
+                block.Difficulty = int(v)
+            }
+        case wireBytes:
+            b, err := r.bytes()
+            if err != nil {
+                return nil, err
+            }
+            switch field {
+            case fieldHash:
+                block.Hash = string(b)
+            case fieldPrevHash:
+                block.PrevHash = string(b)
+            case fieldData:
+                block.Data = string(b)
+            case fieldTx:
+                tx, err := decodeTx(b)
+                if err != nil {
+                    return nil, err
+                }
+                block.Transactions = append(block.Transactions, tx)
+            case fieldMerkle:
+                block.MerkleRoot = string(b)
+            case fieldProposer:
+                block.Proposer = string(b)
+            case fieldSignature:
+                block.Signature = string(b)
+            }
+        default:
+            return nil, fmt.Errorf("field %d: unsupported wire type %d", field, wire)
+        }
+    }
+    return &block, nil
+}
+
+func appendVarintField(out []byte, field int, v uint64) []byte {
+    out = binary.AppendUvarint(out, uint64(field<<3|wireVarint))
+    return binary.AppendUvarint(out, v)
+}
+
+func appendBytesField(out []byte, field int, b []byte) []byte {
+    out = binary.AppendUvarint(out, uint64(field<<3|wireBytes))
+    out = binary.AppendUvarint(out, uint64(len(b)))
+    return append(out, b...)
+}
+
+func zigzag(v int64) uint64 {
+    return uint64((v << 1) ^ (v >> 63))
+}
+
+func unzigzag(v uint64) int64 {
+    return int64(v>>1) ^ -int64(v&1)
+}
+
+type fieldReader struct {
+    data []byte
+    pos  int
+}
+
+func (r *fieldReader) done() bool {
+    return r.pos >= len(r.data)
+}
+
+func (r *fieldReader) varint() (uint64, error) {
+    v, n := binary.Uvarint(r.data[r.pos:])
+    if n <= 0 {
+        return 0, fmt.Errorf("bad varint at offset %d", r.pos)
+    }
+    r.pos += n
+    return v, nil
+}
+
+func (r *fieldReader) key() (int, int, error) {
+    v, err := r.varint()
+    if err != nil {
+        return 0, 0, err
+    }
+    return int(v >> 3), int(v & 7), nil
+}
+
+func (r *fieldReader) bytes() ([]byte, error) {
+    n, err := r.varint()
+    if err != nil {
+        return nil, err
+    }
+    if n > uint64(len(r.data)-r.pos) {
+        return nil, fmt.Errorf("field length %d overruns value", n)
+    }
+    b := r.data[r.pos : r.pos+int(n)]
+    r.pos += int(n)
+    return b, nil
+}
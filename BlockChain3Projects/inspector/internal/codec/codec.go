@@ -0,0 +1,192 @@
+package codec
+
+import (
+    "bytes"
+    "fmt"
+    "sort"
+    "strings"
+
+    "inspector/internal/blocks"
+)
+
+// Codec turns a stored value into a block and back.
+type Codec interface {
+    Name() string
+    Encode(block *blocks.Block) ([]byte, error)
+    Decode(data []byte) (*blocks.Block, error)
+}
+
+// Compressor wraps the bytes of another codec, e.g. "gzip+json".
+type Compressor interface {
+    Name() string
+    Compress(data []byte) ([]byte, error)
+    Decompress(data []byte) ([]byte, error)
+}
+
+// DecodeError reports which codec failed on a value.
+type DecodeError struct {
+    Codec string
+    Err   error
+}
+
+func (e *DecodeError) Error() string {
+    return fmt.Sprintf("%s decode failed: %v", e.Codec, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+    return e.Err
+}
+
+var (
+    codecs      = map[string]Codec{}
+    compressors = map[string]Compressor{}
+)
+
+func Register(c Codec) {
+    codecs[c.Name()] = c
+}
+
+func RegisterCompressor(c Compressor) {
+    compressors[c.Name()] = c
+}
+
+func init() {
+    Register(jsonCodec{})
+    Register(cborCodec{})
+    Register(binaryCodec{})
+    RegisterCompressor(gzipCompressor{})
+    RegisterCompressor(snappyCompressor{})
+}
+
+// Names lists the registered codecs and compressors.
+func Names() []string {
+    names := []string{}
+    for name := range codecs {
+        names = append(names, name)
+    }
+    for name := range compressors {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// Decode decodes data with the named codec, or detects the encoding when
+// name is "" or "auto". It returns the codec actually used, such as
+// "snappy+cbor". Failures are *DecodeError.
+func Decode(data []byte, name string) (*blocks.Block, string, error) {
+    stripped := 0
+    if name == "" || name == "auto" {
+        name, data, stripped = detect(data)
+    }
+    wrappers, base, err := parseName(name)
+    if err != nil {
+        return nil, name, &DecodeError{Codec: name, Err: err}
+    }
+
+    for _, wrapper := range wrappers[stripped:] {
+        data, err = wrapper.Decompress(data)
+        if err != nil {
+            return nil, name, &DecodeError{Codec: wrapper.Name(), Err: err}
+        }
+    }
+
+    block, err := base.Decode(data)
+    if err != nil {
+        return nil, name, &DecodeError{Codec: name, Err: err}
+    }
+    return block, name, nil
+}
+
+// Encode encodes block with the named codec; "" means json.
+func Encode(block *blocks.Block, name string) ([]byte, error) {
+    if name == "" || name == "auto" {
+        name = "json"
+    }
+    wrappers, base, err := parseName(name)
+    if err != nil {
+        return nil, err
+    }
+
+    data, err := base.Encode(block)
+    if err != nil {
+        return nil, err
+    }
+    for i := len(wrappers) - 1; i >= 0; i-- {
+        data, err = wrappers[i].Compress(data)
+        if err != nil {
+            return nil, err
+        }
+    }
+    return data, nil
+}
+
+// parseName splits "gzip+cbor" into its compressors, outermost first, and
+// base codec. A bare compressor name wraps json.
+func parseName(name string) ([]Compressor, Codec, error) {
+    parts := strings.Split(name, "+")
+    wrappers := []Compressor{}
+    for i, part := range parts {
+        if c, ok := codecs[part]; ok {
+            if i != len(parts)-1 {
+                return nil, nil, fmt.Errorf("codec %q must come last in %q", part, name)
+            }
+            return wrappers, c, nil
+        }
+        w, ok := compressors[part]
+        if !ok {
+            return nil, nil, fmt.Errorf("unknown codec %q (known: %s)", part, strings.Join(Names(), ", "))
+        }
+        wrappers = append(wrappers, w)
+    }
+    return wrappers, codecs["json"], nil
+}
+
+// Detect guesses the codec of a stored value from its leading bytes.
+// Values nothing recognises are reported as json so errors read naturally.
+func Detect(data []byte) string {
+    name, _, _ := detect(data)
+    return name
+}
+
+// detect is Detect that also hands back the value with the compressors it
+// had to undo already removed, and how many of them there were, so Decode
+// does not decompress the same value twice.
+func detect(data []byte) (string, []byte, int) {
+    switch {
+    case bytes.HasPrefix(data, gzipMagic):
+        if inner, err := (gzipCompressor{}).Decompress(data); err == nil {
+            name, payload, stripped := detect(inner)
+            return "gzip+" + name, payload, stripped + 1
+        }
+        return "gzip", data, 0
+    case bytes.HasPrefix(data, binaryMagic):
+        return "binary", data, 0
+    case looksLikeJSON(data):
+        return "json", data, 0
+    }
+
+    // Snappy has no magic number, and its length prefix can look like a
+    // CBOR map header, so it is tried before CBOR and only trusted when the
+    // decompressed value is recognisable.
+    if inner, err := (snappyCompressor{}).Decompress(data); err == nil {
+        if looksLikeJSON(inner) || looksLikeCBOR(inner) || bytes.HasPrefix(inner, binaryMagic) {
+            name, payload, stripped := detect(inner)
+            return "snappy+" + name, payload, stripped + 1
+        }
+    }
+    if looksLikeCBOR(data) {
+        return "cbor", data, 0
+    }
+    return "json", data, 0
+}
+
+func looksLikeJSON(data []byte) bool {
+    trimmed := bytes.TrimLeft(data, " \t\r\n")
+    return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// looksLikeCBOR accepts a CBOR map (major type 5) or the self-describe tag.
+func looksLikeCBOR(data []byte) bool {
+    return len(data) > 0 && (data[0]>>5 == 5 || bytes.HasPrefix(data, cborSelfDescribe))
+}
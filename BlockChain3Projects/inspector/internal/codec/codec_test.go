@@ -0,0 +1,79 @@
+package codec
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+
+    "inspector/internal/blocks"
+)
+
+func TestRoundTripWithDetection(t *testing.T) {
+    block := &blocks.Block{
+        Height:      7,
+        Hash:        "abc",
+        PrevHash:    "def",
+        Data:        "payload",
+        Timestamp:   -42,
+        MerkleRoot:  "root",
+        Proposer:    "key",
+        Signature:   "sig",
+        Nonce:       99,
+        Difficulty:  12,
+        Transactions: []blocks.Transaction{
+            {ID: "t1", Sender: "alice", Receiver: "bob", Amount: 5, Nonce: 1},
+            {ID: "t2", Sender: "bob", Receiver: "carol", Amount: -3, Nonce: 2},
+        },
+    }
+    block.DeclareHashVersion(blocks.HashV0)
+
+    for _, name := range []string{"json", "cbor", "binary", "gzip+json", "snappy+cbor", "gzip+binary", "snappy+json"} {
+        data, err := Encode(block, name)
+        if err != nil {
+            t.Fatalf("%s: encode failed: %v", name, err)
+        }
+
+        decoded, detected, err := Decode(data, "")
+        if err != nil {
+            t.Fatalf("%s: decode failed: %v", name, err)
+        }
+        if detected != name {
+            t.Errorf("Expected codec %s to be detected, got %s", name, detected)
+        }
+        if !reflect.DeepEqual(decoded, block) {
+            t.Errorf("%s: round trip changed block: %+v", name, decoded)
+        }
+    }
+}
+
+func TestDetectUnwrapsOnce(t *testing.T) {
+    block := &blocks.Block{Height: 3, Hash: "abc"}
+    inner, _ := Encode(block, "cbor")
+    data, _ := Encode(block, "gzip+snappy+cbor")
+
+    name, payload, stripped := detect(data)
+    if name != "gzip+snappy+cbor" || stripped != 2 || !reflect.DeepEqual(payload, inner) {
+        t.Errorf("Expected the cbor payload under two compressors, got %s, %d, %x", name, stripped, payload)
+    }
+
+    name, payload, stripped = detect(gzipMagic)
+    if name != "gzip" || stripped != 0 || !reflect.DeepEqual(payload, gzipMagic) {
+        t.Errorf("Expected a broken gzip value to be left as is, got %s, %d", name, stripped)
+    }
+}
+
+func TestDecodeErrorNamesCodec(t *testing.T) {
+    _, _, err := Decode([]byte("{broken"), "")
+
+    var decodeErr *DecodeError
+    if !errors.As(err, &decodeErr) {
+        t.Fatalf("Expected DecodeError, got %v", err)
+    }
+    if decodeErr.Codec != "json" {
+        t.Errorf("Expected json codec in error, got %s", decodeErr.Codec)
+    }
+
+    if _, _, err := Decode([]byte("{}"), "xml"); err == nil {
+        t.Error("Expected unknown codec to fail")
+    }
+}
@@ -0,0 +1,90 @@
+package codec
+
+import (
+    "bytes"
+    "compress/gzip"
+    "encoding/json"
+    "io"
+
+    "github.com/fxamacker/cbor/v2"
+    "github.com/golang/snappy"
+
+    "inspector/internal/blocks"
+)
+
+var (
+    gzipMagic        = []byte{0x1f, 0x8b}
+    cborSelfDescribe = []byte{0xd9, 0xd9, 0xf7}
+)
+
+// jsonCodec is the BHIV node's native encoding.
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Encode(block *blocks.Block) ([]byte, error) {
+    return json.Marshal(block)
+}
+
+func (jsonCodec) Decode(data []byte) (*blocks.Block, error) {
+    var block blocks.Block
+    if err := json.Unmarshal(data, &block); err != nil {
+        return nil, err
+    }
+    return &block, nil
+}
+
+// cborCodec uses the block's json field names as CBOR map keys.
+type cborCodec struct{}
+
+func (cborCodec) Name() string { return "cbor" }
+
+func (cborCodec) Encode(block *blocks.Block) ([]byte, error) {
+    return cbor.Marshal(block)
+}
+
+func (cborCodec) Decode(data []byte) (*blocks.Block, error) {
+    var block blocks.Block
+    if err := cbor.Unmarshal(data, &block); err != nil {
+        return nil, err
+    }
+    return &block, nil
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Name() string { return "gzip" }
+
+func (gzipCompressor) Compress(data []byte) ([]byte, error) {
+    var buf bytes.Buffer
+    w := gzip.NewWriter(&buf)
+    if _, err := w.Write(data); err != nil {
+        return nil, err
+    }
+    if err := w.Close(); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(data []byte) ([]byte, error) {
+    r, err := gzip.NewReader(bytes.NewReader(data))
+    if err != nil {
+        return nil, err
+    }
+    defer r.Close()
+    return io.ReadAll(r)
+}
+
+// snappyCompressor uses the snappy block format, as LevelDB-based nodes do.
+type snappyCompressor struct{}
+
+func (snappyCompressor) Name() string { return "snappy" }
+
+func (snappyCompressor) Compress(data []byte) ([]byte, error) {
+    return snappy.Encode(nil, data), nil
+}
+
+func (snappyCompressor) Decompress(data []byte) ([]byte, error) {
+    return snappy.Decode(nil, data)
+}
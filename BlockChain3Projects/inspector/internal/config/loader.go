@@ -4,16 +4,39 @@ import (
     "encoding/json"
     "fmt"
     "os"
+    "path/filepath"
+
+    "inspector/internal/blocks"
+    "inspector/internal/db"
+    "inspector/internal/rules"
 )
 
 type NetworkConfig struct {
-    Nodes []NodeConfig `json:"nodes"`
+    HashVersion   int               `json:"hash_version,omitempty"`
+    HashAlgorithm string            `json:"hash_algorithm,omitempty"`
+    Validators    string            `json:"validators,omitempty"`
+    Pow           *blocks.PowParams `json:"pow,omitempty"`
+    ChainSpec     string            `json:"chain_spec,omitempty"`
+    Policy        string            `json:"policy,omitempty"`
+    Rules         *RuleSelection    `json:"rules,omitempty"`
+    Nodes         []NodeConfig      `json:"nodes"`
+}
+
+// RuleSelection picks the scan rules by id: only those in Enable when it is
+// set, never those in Disable.
+type RuleSelection struct {
+    Enable  []string `json:"enable,omitempty"`
+    Disable []string `json:"disable,omitempty"`
 }
 
 type NodeConfig struct {
-    Name   string `json:"name"`
-    DBPath string `json:"db_path"`
-    RPCURL string `json:"rpc_url,omitempty"`
+    Name          string        `json:"name"`
+    DBPath        string        `json:"db_path"`
+    RPCURL        string        `json:"rpc_url,omitempty"`
+    KeySchema     *db.KeySchema `json:"key_schema,omitempty"`
+    Codec         string        `json:"codec,omitempty"`
+    HashVersion   *int          `json:"hash_version,omitempty"`
+    HashAlgorithm string        `json:"hash_algorithm,omitempty"`
 }
 
 func LoadConfig(path string) (*NetworkConfig, error) {
@@ -30,6 +53,73 @@ func LoadConfig(path string) (*NetworkConfig, error) {
     if len(config.Nodes) == 0 {
         return nil, fmt.Errorf("no nodes defined in config")
     }
+    // Files named by the config are relative to it, not to the working
+    // directory the inspector happens to run in.
+    for _, ref := range []*string{&config.Validators, &config.ChainSpec, &config.Policy} {
+        if *ref != "" && !filepath.IsAbs(*ref) {
+            *ref = filepath.Join(filepath.Dir(path), *ref)
+        }
+    }
+    if !blocks.ValidHashVersion(config.HashVersion) {
+        return nil, fmt.Errorf("unknown hash_version %d", config.HashVersion)
+    }
+    if _, err := blocks.HashAlgorithm(config.HashAlgorithm); err != nil {
+        return nil, err
+    }
+    if config.Pow != nil {
+        if err := config.Pow.Validate(); err != nil {
+            return nil, err
+        }
+    }
+    if config.Rules != nil {
+        if _, err := rules.Select(config.Rules.Enable, config.Rules.Disable); err != nil {
+            return nil, err
+        }
+    }
+
+    for _, node := range config.Nodes {
+        if node.DBPath == "" && node.RPCURL == "" {
+            return nil, fmt.Errorf("node %q needs a db_path or rpc_url", node.Name)
+        }
+        if node.HashVersion != nil && !blocks.ValidHashVersion(*node.HashVersion) {
+            return nil, fmt.Errorf("node %q: unknown hash_version %d", node.Name, *node.HashVersion)
+        }
+        if _, err := blocks.HashAlgorithm(node.HashAlgorithm); node.HashAlgorithm != "" && err != nil {
+            return nil, fmt.Errorf("node %q: %w", node.Name, err)
+        }
+    }
 
     return &config, nil
 }
+
+// FindNode returns the configured node reading from dbPath or rpcURL, or nil.
+func (c *NetworkConfig) FindNode(dbPath, rpcURL string) *NodeConfig {
+    for i := range c.Nodes {
+        node := &c.Nodes[i]
+        if rpcURL != "" && node.RPCURL == rpcURL {
+            return node
+        }
+        if rpcURL == "" && node.DBPath != "" && filepath.Clean(node.DBPath) == filepath.Clean(dbPath) {
+            return node
+        }
+    }
+    return nil
+}
+
+// NodeHashVersion is the hash scheme of the node's chain: its own
+// hash_version if set, otherwise the network's.
+func (c *NetworkConfig) NodeHashVersion(node *NodeConfig) int {
+    if node != nil && node.HashVersion != nil {
+        return *node.HashVersion
+    }
+    return c.HashVersion
+}
+
+// NodeHashAlgorithm is the block hash algorithm of the node's chain: its own
+// hash_algorithm if set, otherwise the network's ("" meaning sha256).
+func (c *NetworkConfig) NodeHashAlgorithm(node *NodeConfig) string {
+    if node != nil && node.HashAlgorithm != "" {
+        return node.HashAlgorithm
+    }
+    return c.HashAlgorithm
+}
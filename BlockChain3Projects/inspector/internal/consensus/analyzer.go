@@ -2,16 +2,40 @@ package consensus
 
 import (
     "fmt"
+    "math/big"
+    "sort"
     "time"
 
+    "inspector/internal/blocks"
+    "inspector/internal/chainspec"
     "inspector/internal/db"
+    "inspector/internal/health"
+)
+
+// Node problems the network health score charges, usable as rule_weights
+// in the health model.
+const (
+    Unreachable  = "unreachable"
+    WrongChain   = "wrong_chain"
+    OffCanonical = "off_canonical"
+    SpecMismatch = "spec_mismatch"
+    FarBehind    = "far_behind"
+    Behind       = "behind"
+    FetchErrors  = "fetch_errors"
 )
 
 type NodeInfo struct {
-    Name      string
-    DBPath    string
-    Height    int
-    Storage   *db.Storage
+    Name       string
+    DBPath     string
+    RPCURL     string
+    Height     int
+    Source     db.BlockSource
+    Spec       *chainspec.Spec
+    Err        error
+    // HashVersion and HashAlgorithm are the node's hash scheme, which work
+    // is only counted for blocks that verify under.
+    HashVersion   int
+    HashAlgorithm string
 }
 
 type ConsensusResult struct {
@@ -23,6 +47,7 @@ type ConsensusResult struct {
     NodeStates          map[string]NodeState `json:"node_states"`
     Recommendations     []string            `json:"recommendations"`
     NetworkHealth       string              `json:"network_health"`
+    Health              health.Score        `json:"health"`
 }
 
 type ForkPoint struct {
@@ -32,13 +57,26 @@ type ForkPoint struct {
 }
 
 type NodeState struct {
-    Height       int      `json:"height"`
-    Status       string   `json:"status"`
-    BlocksBehind int      `json:"blocks_behind"`
-    OnCanonical  bool     `json:"on_canonical"`
+    Height           int    `json:"height"`
+    ContiguousHeight int    `json:"contiguous_height"`
+    Status           string `json:"status"`
+    Source           string `json:"source"`
+    BlocksBehind     int    `json:"blocks_behind"`
+    OnCanonical      bool   `json:"on_canonical"`
+    CumulativeWork   string `json:"cumulative_work"`
+    FetchErrors      int      `json:"fetch_errors,omitempty"`
+    SpecErrors       []string `json:"spec_errors,omitempty"`
+    Error            string   `json:"error,omitempty"`
 }
 
 func AnalyzeConsensus(nodes []NodeInfo) (*ConsensusResult, error) {
+    return AnalyzeConsensusWithModel(nodes, health.DefaultModel())
+}
+
+// AnalyzeConsensusWithModel is AnalyzeConsensus with the network health
+// scored by model, each node being a unit.
+func AnalyzeConsensusWithModel(nodes []NodeInfo, model health.Model) (*ConsensusResult, error) {
+    all := nodes
     result := &ConsensusResult{
         Timestamp:  time.Now().Format("2006-01-02 15:04:05"),
         TotalNodes: len(nodes),
@@ -50,6 +88,96 @@ func AnalyzeConsensus(nodes []NodeInfo) (*ConsensusResult, error) {
         return result, fmt.Errorf("no nodes provided")
     }
 
+    // Nodes that could not be opened are reported, not analyzed.
+    reachable := []NodeInfo{}
+    for _, node := range nodes {
+        if node.Err != nil {
+            result.NodeStates[node.Name] = NodeState{
+                Height: -1,
+                Status: "unreachable",
+                Source: nodeSource(node),
+                Error:  node.Err.Error(),
+            }
+            continue
+        }
+        reachable = append(reachable, node)
+    }
+    nodes = reachable
+
+    if len(nodes) == 0 {
+        return result, fmt.Errorf("no reachable nodes")
+    }
+
+    // One sequential pass per node; chainHashes[name] holds the heights the
+    // node has a readable block at, which past a gap may be few and far up,
+    // and contiguous[name] the last height before its first gap.
+    chainHashes := make(map[string]map[int]string)
+    contiguous := make(map[string]int)
+    fetchErrors := make(map[string][]error)
+    chainWork := make(map[string]*big.Int)
+    specErrors := make(map[string][]string)
+    
+    for _, node := range nodes {
+        hashes := make(map[int]string)
+        work := new(big.Int)
+        unbroken := -1
+        err := node.Source.IterateBlocks(0, node.Height, func(height int, block *blocks.Block, err error) bool {
+            if height == unbroken+1 {
+                unbroken = height
+            }
+            if err != nil {
+                fetchErrors[node.Name] = append(fetchErrors[node.Name],
+                    fmt.Errorf("block %d: %w", height, err))
+                return true
+            }
+            hashes[height] = block.Hash
+            work.Add(work, blocks.ProvenWork(block, node.HashVersion, node.HashAlgorithm))
+            return true
+        })
+        if err != nil {
+            fetchErrors[node.Name] = append(fetchErrors[node.Name], err)
+        }
+        chainHashes[node.Name] = hashes
+        contiguous[node.Name] = unbroken
+        chainWork[node.Name] = work
+    }
+
+    // A node whose genesis does not match its chain spec holds another
+    // chain altogether and would only show up as a fork at height 0.
+    onChain := []NodeInfo{}
+    for _, node := range nodes {
+        if node.Spec == nil {
+            onChain = append(onChain, node)
+            continue
+        }
+        hashes := chainHashes[node.Name]
+        mismatches := node.Spec.CheckHashes(node.Height, func(height int) (string, bool) {
+            hash, ok := hashes[height]
+            return hash, ok
+        })
+        for _, m := range mismatches {
+            specErrors[node.Name] = append(specErrors[node.Name], fmt.Sprintf("block %d: %s", m.Height, m))
+        }
+        if chainspec.WrongChain(mismatches) {
+            result.NodeStates[node.Name] = NodeState{
+                Height:           node.Height,
+                ContiguousHeight: contiguous[node.Name],
+                Status:           "wrong_chain",
+                Source:           nodeSource(node),
+                CumulativeWork:   chainWork[node.Name].String(),
+                SpecErrors:       specErrors[node.Name],
+                Error:            fmt.Sprintf("not on chain %s", node.Spec.ChainID),
+            }
+            continue
+        }
+        onChain = append(onChain, node)
+    }
+    nodes = onChain
+
+    if len(nodes) == 0 {
+        return result, fmt.Errorf("no nodes on the expected chain")
+    }
+
     maxHeight := 0
     for _, node := range nodes {
         if node.Height > maxHeight {
@@ -57,25 +185,24 @@ func AnalyzeConsensus(nodes []NodeInfo) (*ConsensusResult, error) {
         }
     }
     
+    // consensusMap[height][hash] lists the nodes holding hash at height,
+    // for the heights some node holds a block at.
     consensusMap := make(map[int]map[string][]string)
-    
-    for height := 0; height <= maxHeight; height++ {
-        consensusMap[height] = make(map[string][]string)
-        
-        for _, node := range nodes {
-            if height <= node.Height {
-                block, err := node.Storage.LoadBlock(height)
-                if err == nil {
-                    consensusMap[height][block.Hash] = append(
-                        consensusMap[height][block.Hash],
-                        node.Name,
-                    )
-                }
+    for _, node := range nodes {
+        for height, hash := range chainHashes[node.Name] {
+            if consensusMap[height] == nil {
+                consensusMap[height] = make(map[string][]string)
             }
+            consensusMap[height][hash] = append(consensusMap[height][hash], node.Name)
         }
     }
+    heights := make([]int, 0, len(consensusMap))
+    for height := range consensusMap {
+        heights = append(heights, height)
+    }
+    sort.Ints(heights)
 
-    for height := 0; height <= maxHeight; height++ {
+    for _, height := range heights {
         if len(consensusMap[height]) > 1 {
             affectedNodes := []string{}
             for _, nodeList := range consensusMap[height] {
@@ -90,31 +217,36 @@ func AnalyzeConsensus(nodes []NodeInfo) (*ConsensusResult, error) {
         }
     }
 
-    result.CanonicalChain = findCanonicalChain(nodes, consensusMap)
-    result.ConsensusHeight = findConsensusHeight(consensusMap, maxHeight)
+    result.CanonicalChain = findCanonicalChain(nodes, chainHashes, consensusMap)
+    result.ConsensusHeight = findConsensusHeight(consensusMap, heights)
+    forked := forkedNodes(consensusMap, result.ForkPoints, chainHashes[result.CanonicalChain])
 
     for _, node := range nodes {
-        state := analyzeNodeState(node, result.CanonicalChain, maxHeight)
+        state := analyzeNodeState(node, !forked[node.Name], maxHeight, contiguous[node.Name])
+        state.CumulativeWork = chainWork[node.Name].String()
+        state.SpecErrors = specErrors[node.Name]
+        if errs := fetchErrors[node.Name]; len(errs) > 0 {
+            state.FetchErrors = len(errs)
+            state.Error = errs[0].Error()
+        }
         result.NodeStates[node.Name] = state
     }
 
     result.Recommendations = generateConsensusRecommendations(result)
-    result.NetworkHealth = calculateNetworkHealth(result)
+    result.Health = scoreNetwork(model, all, result.NodeStates)
+    result.NetworkHealth = result.Health.Grade
 
     return result, nil
 }
 
-func findCanonicalChain(nodes []NodeInfo, consensusMap map[int]map[string][]string) string {
+func findCanonicalChain(nodes []NodeInfo, chainHashes map[string]map[int]string, consensusMap map[int]map[string][]string) string {
     maxScore := 0
     canonical := ""
     
     for _, node := range nodes {
         score := 0
-        for height := 0; height <= node.Height; height++ {
-            block, err := node.Storage.LoadBlock(height)
-            if err == nil {
-                score += len(consensusMap[height][block.Hash])
-            }
+        for height, hash := range chainHashes[node.Name] {
+            score += len(consensusMap[height][hash])
         }
         
         if score > maxScore {
@@ -126,20 +258,51 @@ func findCanonicalChain(nodes []NodeInfo, consensusMap map[int]map[string][]stri
     return canonical
 }
 
-func findConsensusHeight(consensusMap map[int]map[string][]string, maxHeight int) int {
-    for height := maxHeight; height >= 0; height-- {
-        if len(consensusMap[height]) == 1 {
-            return height
+// forkedNodes returns the nodes some fork point finds on another branch
+// than the canonical node's, or, at a height the canonical node holds no
+// block at, than the branch most nodes are on.
+func forkedNodes(consensusMap map[int]map[string][]string, forks []ForkPoint, canonical map[int]string) map[string]bool {
+    forked := make(map[string]bool)
+    for _, fork := range forks {
+        branches := consensusMap[fork.Height]
+        want, ok := canonical[fork.Height]
+        if !ok {
+            for hash, nodeList := range branches {
+                if len(nodeList) > len(branches[want]) || len(nodeList) == len(branches[want]) && hash < want {
+                    want = hash
+                }
+            }
+        }
+        for hash, nodeList := range branches {
+            if hash == want {
+                continue
+            }
+            for _, name := range nodeList {
+                forked[name] = true
+            }
+        }
+    }
+    return forked
+}
+
+// findConsensusHeight is the highest of heights, in ascending order, that
+// every node holding a block at agrees on.
+func findConsensusHeight(consensusMap map[int]map[string][]string, heights []int) int {
+    for i := len(heights) - 1; i >= 0; i-- {
+        if len(consensusMap[heights[i]]) == 1 {
+            return heights[i]
         }
     }
     return 0
 }
 
-func analyzeNodeState(node NodeInfo, canonical string, maxHeight int) NodeState {
+func analyzeNodeState(node NodeInfo, onCanonical bool, maxHeight, contiguous int) NodeState {
     state := NodeState{
-        Height:       node.Height,
-        BlocksBehind: maxHeight - node.Height,
-        OnCanonical:  (node.Name == canonical),
+        Height:           node.Height,
+        ContiguousHeight: contiguous,
+        Source:           nodeSource(node),
+        BlocksBehind:     maxHeight - node.Height,
+        OnCanonical:      onCanonical,
     }
 
     if node.Height == maxHeight {
@@ -153,6 +316,13 @@ func analyzeNodeState(node NodeInfo, canonical string, maxHeight int) NodeState
     return state
 }
 
+func nodeSource(node NodeInfo) string {
+    if node.DBPath == "" && node.RPCURL != "" {
+        return node.RPCURL
+    }
+    return node.DBPath
+}
+
 func generateConsensusRecommendations(result *ConsensusResult) []string {
     recs := []string{}
 
@@ -165,6 +335,20 @@ func generateConsensusRecommendations(result *ConsensusResult) []string {
     }
 
     for name, state := range result.NodeStates {
+        if state.Status == "unreachable" {
+            recs = append(recs, fmt.Sprintf("🔌 %s: Unreachable (%s) - %s", name, state.Source, state.Error))
+            continue
+        }
+        if state.Status == "wrong_chain" {
+            recs = append(recs, fmt.Sprintf("⛔ %s: Genesis does not match the chain spec - %s, excluded from consensus", name, state.Error))
+            continue
+        }
+        if len(state.SpecErrors) > 0 {
+            recs = append(recs, fmt.Sprintf("⛔ %s: Fails %d checkpoint(s) - %s", name, len(state.SpecErrors), state.SpecErrors[0]))
+        }
+        if state.FetchErrors > 0 {
+            recs = append(recs, fmt.Sprintf("⚠️  %s: %d block(s) could not be read - %s", name, state.FetchErrors, state.Error))
+        }
         if !state.OnCanonical {
             recs = append(recs, fmt.Sprintf("🔧 %s: Resync from canonical chain (%s)", name, result.CanonicalChain))
         }
@@ -182,29 +366,37 @@ func generateConsensusRecommendations(result *ConsensusResult) []string {
     return recs
 }
 
-func calculateNetworkHealth(result *ConsensusResult) string {
-    if len(result.ForkPoints) > 3 {
-        return "CRITICAL"
-    } else if len(result.ForkPoints) > 0 {
-        return "WARNING"
-    }
-    
-    syncedNodes := 0
-    for _, state := range result.NodeStates {
-        if state.BlocksBehind == 0 && state.OnCanonical {
-            syncedNodes++
+// scoreNetwork charges each node for its worst problem. Forks count through
+// the nodes they leave off the canonical chain.
+func scoreNetwork(model health.Model, nodes []NodeInfo, states map[string]NodeState) health.Score {
+    issues := []health.Issue{}
+    for unit, node := range nodes {
+        state := states[node.Name]
+        add := func(category, severity string) {
+            issues = append(issues, health.Issue{Unit: unit, Category: category, Severity: severity})
+        }
+        switch state.Status {
+        case "unreachable":
+            add(Unreachable, "critical")
+            continue
+        case "wrong_chain":
+            add(WrongChain, "critical")
+            continue
+        }
+        if !state.OnCanonical {
+            add(OffCanonical, "error")
+        }
+        if len(state.SpecErrors) > 0 {
+            add(SpecMismatch, "error")
+        }
+        if state.BlocksBehind > 10 {
+            add(FarBehind, "error")
+        } else if state.BlocksBehind > 0 {
+            add(Behind, "warning")
+        }
+        if state.FetchErrors > 0 {
+            add(FetchErrors, "warning")
         }
     }
-    
-    syncPercentage := float64(syncedNodes) / float64(result.TotalNodes) * 100
-    
-    if syncPercentage >= 90 {
-        return "EXCELLENT"
-    } else if syncPercentage >= 70 {
-        return "GOOD"
-    } else if syncPercentage >= 50 {
-        return "FAIR"
-    } else {
-        return "POOR"
-    }
+    return model.Score(len(nodes), issues)
 }
@@ -0,0 +1,57 @@
+package consensus
+
+import (
+    "testing"
+
+    "inspector/internal/blocks"
+    "inspector/internal/db"
+)
+
+func node(t *testing.T, name string, chain []*blocks.Block) NodeInfo {
+    source, err := db.NewMemorySource(chain)
+    if err != nil {
+        t.Fatal(err)
+    }
+    tip, _ := source.Tip()
+    return NodeInfo{Name: name, DBPath: name, Height: tip.Highest, Source: source}
+}
+
+func TestConsensusWalksStoredHeightsOnly(t *testing.T) {
+    chain := []*blocks.Block{{Height: 0, Hash: "a0"}, {Height: 1, Hash: "a1", PrevHash: "a0"}}
+    stray := &blocks.Block{Height: 1 << 40, Hash: "far"}
+    nodes := []NodeInfo{node(t, "node1", append(chain[:2:2], stray)), node(t, "node2", chain)}
+
+    result, err := AnalyzeConsensus(nodes)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(result.ForkPoints) != 0 || result.ConsensusHeight != 1<<40 || result.NodeStates["node2"].BlocksBehind != 1<<40-1 {
+        t.Errorf("Expected agreement up to the stray block, got %+v", result)
+    }
+    if state := result.NodeStates["node1"]; state.ContiguousHeight != 1 {
+        t.Errorf("Expected node1 contiguous to 1, got %d", state.ContiguousHeight)
+    }
+
+    forked := []*blocks.Block{chain[0], {Height: 1, Hash: "b1", PrevHash: "a0"}}
+    result, _ = AnalyzeConsensus([]NodeInfo{nodes[0], node(t, "node2", forked)})
+    if len(result.ForkPoints) != 1 || result.ForkPoints[0].Height != 1 || result.ForkPoints[0].Branches != 2 {
+        t.Errorf("Expected one fork at height 1, got %+v", result.ForkPoints)
+    }
+}
+
+func TestScoreCountsForks(t *testing.T) {
+    chain := []*blocks.Block{{Height: 0, Hash: "a0"}, {Height: 1, Hash: "a1", PrevHash: "a0"}}
+    agreed, _ := AnalyzeConsensus([]NodeInfo{node(t, "node1", chain), node(t, "node2", chain), node(t, "node3", chain)})
+    if agreed.Health.Score != 100 || !agreed.NodeStates["node2"].OnCanonical || !agreed.NodeStates["node3"].OnCanonical {
+        t.Errorf("Expected nodes that agree all on the canonical chain, got %+v", agreed.Health)
+    }
+
+    forked := []*blocks.Block{chain[0], {Height: 1, Hash: "b1", PrevHash: "a0"}}
+    result, _ := AnalyzeConsensus([]NodeInfo{node(t, "node1", chain), node(t, "node2", chain), node(t, "node3", forked)})
+    if result.NodeStates["node3"].OnCanonical || !result.NodeStates["node2"].OnCanonical {
+        t.Errorf("Expected only node3 off the canonical chain, got %+v", result.NodeStates)
+    }
+    if b := result.Health.Breakdown; len(b) != 1 || b[0].Category != OffCanonical || b[0].Issues != 1 || result.Health.Score >= 100 {
+        t.Errorf("Expected the fork to cost node3, got %+v", result.Health)
+    }
+}
@@ -23,7 +23,7 @@ func outputConsensusText(result *ConsensusResult) {
     fmt.Printf("\n📊 NETWORK OVERVIEW:\n")
     fmt.Printf("  Scan Time:         %s\n", result.Timestamp)
     fmt.Printf("  Total Nodes:       %d\n", result.TotalNodes)
-    fmt.Printf("  Network Health:    %s\n", getHealthEmoji(result.NetworkHealth))
+    fmt.Printf("  Network Health:    %s (%.1f/100)\n", getHealthEmoji(result.NetworkHealth), result.Health.Score)
     fmt.Printf("  Canonical Chain:   %s\n", result.CanonicalChain)
     fmt.Printf("  Consensus Height:  %d\n", result.ConsensusHeight)
     fmt.Printf("  Fork Points:       %d\n", len(result.ForkPoints))
@@ -38,15 +38,42 @@ func outputConsensusText(result *ConsensusResult) {
         }
     }
     
+    if len(result.Health.Breakdown) > 0 {
+        fmt.Printf("\n🩺 HEALTH BREAKDOWN (%d of %d nodes affected, each charged for its worst problem):\n",
+            result.Health.Affected, result.Health.Units)
+        for _, line := range result.Health.Explain() {
+            fmt.Printf("  %s\n", line)
+        }
+    }
+
     fmt.Println("\n🖥️  NODE STATUS:")
     for name, state := range result.NodeStates {
         statusIcon := getNodeStatusIcon(state)
         
         fmt.Printf("  %s %s:\n", statusIcon, name)
+        fmt.Printf("      Source:        %s\n", state.Source)
+        if state.Status == "unreachable" || state.Status == "wrong_chain" {
+            fmt.Printf("      Status:        %s\n", state.Status)
+            fmt.Printf("      Error:         %s\n", state.Error)
+            for _, specErr := range state.SpecErrors {
+                fmt.Printf("      Spec:          %s\n", specErr)
+            }
+            continue
+        }
         fmt.Printf("      Height:        %d\n", state.Height)
+        if state.ContiguousHeight != state.Height {
+            fmt.Printf("      Contiguous:    %d (gap above)\n", state.ContiguousHeight)
+        }
         fmt.Printf("      Status:        %s\n", state.Status)
         fmt.Printf("      Blocks Behind: %d\n", state.BlocksBehind)
         fmt.Printf("      On Canonical:  %v\n", state.OnCanonical)
+        fmt.Printf("      Work:          %s\n", state.CumulativeWork)
+        if state.FetchErrors > 0 {
+            fmt.Printf("      Fetch Errors:  %d (%s)\n", state.FetchErrors, state.Error)
+        }
+        for _, specErr := range state.SpecErrors {
+            fmt.Printf("      Spec:          %s\n", specErr)
+        }
     }
     
     fmt.Println("\n💡 RECOMMENDATIONS:")
@@ -78,7 +105,11 @@ func getHealthEmoji(health string) string {
 }
 
 func getNodeStatusIcon(state NodeState) string {
-    if !state.OnCanonical {
+    if state.Status == "unreachable" {
+        return "🔌"
+    } else if state.Status == "wrong_chain" {
+        return "⛔"
+    } else if !state.OnCanonical {
         return "❌"
     } else if state.BlocksBehind > 10 {
         return "🔴"
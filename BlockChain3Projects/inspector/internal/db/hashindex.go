@@ -0,0 +1,188 @@
+package db
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+
+    "inspector/internal/blocks"
+)
+
+// IndexEntry is one block as seen by a HashIndex.
+type IndexEntry struct {
+    Height   int    `json:"height"`
+    Hash     string `json:"hash"`
+    PrevHash string `json:"prev_hash"`
+}
+
+// HashIndex maps block hashes to heights, and parents to their children,
+// for one chain. It is built from a single pass over a BlockSource, which
+// also notes the heights whose value could not be decoded.
+type HashIndex struct {
+    entries    map[int]IndexEntry
+    byHash     map[string][]int
+    children   map[string][]int
+    sorted     []string
+    unreadable []int
+}
+
+func BuildHashIndex(source BlockSource) (*HashIndex, error) {
+    tip, err := source.Tip()
+    if err != nil {
+        return nil, err
+    }
+
+    idx := &HashIndex{
+        entries:  make(map[int]IndexEntry),
+        byHash:   make(map[string][]int),
+        children: make(map[string][]int),
+    }
+    err = source.IterateBlocks(0, tip.Highest, func(height int, block *blocks.Block, err error) bool {
+        if err != nil {
+            idx.unreadable = append(idx.unreadable, height)
+            return true
+        }
+        entry := IndexEntry{Height: height, Hash: block.Hash, PrevHash: block.PrevHash}
+        idx.entries[height] = entry
+        hash, parent := hashKey(entry.Hash), hashKey(entry.PrevHash)
+        idx.byHash[hash] = append(idx.byHash[hash], height)
+        idx.children[parent] = append(idx.children[parent], height)
+        return true
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    idx.sorted = make([]string, 0, len(idx.byHash))
+    for hash := range idx.byHash {
+        idx.sorted = append(idx.sorted, hash)
+    }
+    sort.Strings(idx.sorted)
+    return idx, nil
+}
+
+// hashKey is how hashes are keyed in the index: hex digests compare the same
+// whatever case a node stored them in.
+func hashKey(hash string) string {
+    return strings.ToLower(hash)
+}
+
+// IndexOf returns the index a source keeps for itself, or builds one.
+func IndexOf(source BlockSource) (*HashIndex, error) {
+    if indexed, ok := source.(interface{ HashIndex() (*HashIndex, error) }); ok {
+        return indexed.HashIndex()
+    }
+    return BuildHashIndex(source)
+}
+
+func (idx *HashIndex) Len() int {
+    return len(idx.entries)
+}
+
+// Entries returns every indexed block in height order.
+func (idx *HashIndex) Entries() []IndexEntry {
+    entries := make([]IndexEntry, 0, len(idx.entries))
+    for _, entry := range idx.entries {
+        entries = append(entries, entry)
+    }
+    sort.Slice(entries, func(i, j int) bool { return entries[i].Height < entries[j].Height })
+    return entries
+}
+
+// Unreadable returns the heights holding a value that did not decode, in
+// height order.
+func (idx *HashIndex) Unreadable() []int {
+    return append([]int(nil), idx.unreadable...)
+}
+
+// Lookup returns every block stored under exactly hash, lowest height first.
+func (idx *HashIndex) Lookup(hash string) []IndexEntry {
+    found := []IndexEntry{}
+    for _, height := range idx.byHash[hashKey(hash)] {
+        found = append(found, idx.entries[height])
+    }
+    return found
+}
+
+// Has reports whether any block has the given hash.
+func (idx *HashIndex) Has(hash string) bool {
+    return len(idx.byHash[hashKey(hash)]) > 0
+}
+
+// Resolve finds the block for a full hash or a unique hash prefix. When a
+// hash is stored at several heights the lowest one is returned.
+func (idx *HashIndex) Resolve(hashOrPrefix string) (IndexEntry, error) {
+    query := hashKey(strings.TrimSpace(hashOrPrefix))
+    if query == "" {
+        return IndexEntry{}, fmt.Errorf("empty hash")
+    }
+    if heights := idx.byHash[query]; len(heights) > 0 {
+        return idx.entries[heights[0]], nil
+    }
+
+    matches := []string{}
+    for i := sort.SearchStrings(idx.sorted, query); i < len(idx.sorted); i++ {
+        if !strings.HasPrefix(idx.sorted[i], query) {
+            break
+        }
+        matches = append(matches, idx.sorted[i])
+    }
+
+    switch len(matches) {
+    case 0:
+        return IndexEntry{}, fmt.Errorf("no block with hash %s among %d blocks", hashOrPrefix, idx.Len())
+    case 1:
+        return idx.entries[idx.byHash[matches[0]][0]], nil
+    }
+    if len(matches) > 5 {
+        matches = append(matches[:5], "...")
+    }
+    return IndexEntry{}, fmt.Errorf("hash prefix %s is ambiguous: %s", hashOrPrefix, strings.Join(matches, ", "))
+}
+
+// Ancestors follows PrevHash links from hash for up to n steps, nearest
+// parent first. The walk stops at a parent that is not in the index.
+func (idx *HashIndex) Ancestors(hash string, n int) []IndexEntry {
+    ancestors := []IndexEntry{}
+    current, err := idx.Resolve(hash)
+    if err != nil {
+        return ancestors
+    }
+    for len(ancestors) < n {
+        parents := idx.byHash[hashKey(current.PrevHash)]
+        if len(parents) == 0 {
+            break
+        }
+        current = idx.entries[parents[0]]
+        ancestors = append(ancestors, current)
+    }
+    return ancestors
+}
+
+// Descendants walks child links from hash breadth-first, up to n levels
+// deep, so side branches show up next to the main line.
+func (idx *HashIndex) Descendants(hash string, n int) []IndexEntry {
+    descendants := []IndexEntry{}
+    start, err := idx.Resolve(hash)
+    if err != nil {
+        return descendants
+    }
+
+    level := []string{hashKey(start.Hash)}
+    seen := map[string]bool{level[0]: true}
+    for depth := 0; depth < n && len(level) > 0; depth++ {
+        next := []string{}
+        for _, parent := range level {
+            for _, height := range idx.children[parent] {
+                child := idx.entries[height]
+                descendants = append(descendants, child)
+                if hash := hashKey(child.Hash); !seen[hash] {
+                    seen[hash] = true
+                    next = append(next, hash)
+                }
+            }
+        }
+        level = next
+    }
+    return descendants
+}
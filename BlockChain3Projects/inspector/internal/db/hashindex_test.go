@@ -0,0 +1,65 @@
+package db
+
+import (
+    "testing"
+
+    "inspector/internal/blocks"
+)
+
+func TestHashIndexLookups(t *testing.T) {
+    source, _ := NewMemorySource([]*blocks.Block{
+        {Height: 0, Hash: "aa00", PrevHash: "0"},
+        {Height: 1, Hash: "ab11", PrevHash: "aa00"},
+        {Height: 2, Hash: "bc22", PrevHash: "ab11"},
+        {Height: 3, Hash: "cd33", PrevHash: "bc22"},
+        {Height: 4, Hash: "de44", PrevHash: "ab11"},
+    })
+
+    index, err := IndexOf(source)
+    if err != nil {
+        t.Fatalf("Failed to build index: %v", err)
+    }
+
+    entry, err := index.Resolve("BC")
+    if err != nil || entry.Height != 2 {
+        t.Errorf("Expected prefix bc to resolve to height 2, got %+v (%v)", entry, err)
+    }
+    if _, err := index.Resolve("a"); err == nil {
+        t.Error("Expected ambiguous prefix to fail")
+    }
+    if _, err := index.Resolve("ff"); err == nil {
+        t.Error("Expected unknown hash to fail")
+    }
+
+    ancestors := index.Ancestors("cd33", 10)
+    if len(ancestors) != 3 || ancestors[0].Hash != "bc22" || ancestors[2].Hash != "aa00" {
+        t.Errorf("Unexpected ancestors %+v", ancestors)
+    }
+
+    descendants := index.Descendants("ab11", 1)
+    if len(descendants) != 2 {
+        t.Errorf("Expected both children of ab11, got %+v", descendants)
+    }
+}
+
+func TestHashIndexIgnoresStoredCase(t *testing.T) {
+    source, _ := NewMemorySource([]*blocks.Block{
+        {Height: 0, Hash: "AA00", PrevHash: "0"},
+        {Height: 1, Hash: "BB11", PrevHash: "aa00"},
+        {Height: 2, Hash: "cc22", PrevHash: "BB11"},
+    })
+    index, _ := IndexOf(source)
+
+    if entry, err := index.Resolve("bb"); err != nil || entry.Height != 1 {
+        t.Errorf("Expected prefix bb to resolve to height 1, got %+v (%v)", entry, err)
+    }
+    if !index.Has("aa00") || len(index.Lookup("Cc22")) != 1 {
+        t.Error("Expected lookups to ignore case")
+    }
+    if ancestors := index.Ancestors("cc22", 10); len(ancestors) != 2 {
+        t.Errorf("Expected two ancestors across mixed case, got %+v", ancestors)
+    }
+    if descendants := index.Descendants("AA00", 2); len(descendants) != 2 {
+        t.Errorf("Expected two descendants across mixed case, got %+v", descendants)
+    }
+}
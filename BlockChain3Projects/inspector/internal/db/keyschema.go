@@ -0,0 +1,111 @@
+package db
+
+import (
+    "encoding/binary"
+    "fmt"
+    "strconv"
+)
+
+// Key encodings understood by KeySchema.
+const (
+    EncodingDecimal  = "decimal"
+    EncodingPadded   = "padded"
+    EncodingUint64BE = "uint64be"
+)
+
+const defaultPaddedWidth = 20
+
+// KeySchema describes how a node lays out block keys. The zero value is the
+// BHIV layout: blocks stored under "block-<height>" in plain decimal.
+//
+// When HashPrefix is set the layout is two-level: keys under Prefix hold the
+// block hash for each height, and the block itself lives under
+// HashPrefix+hash, using the index value verbatim.
+type KeySchema struct {
+    Prefix     string `json:"prefix,omitempty"`
+    Encoding   string `json:"encoding,omitempty"`
+    Width      int    `json:"width,omitempty"`
+    HashPrefix string `json:"hash_prefix,omitempty"`
+}
+
+// normalized fills in defaults and rejects unknown encodings.
+func (ks KeySchema) normalized() (KeySchema, error) {
+    if ks.Prefix == "" {
+        ks.Prefix = "block-"
+    }
+    switch ks.Encoding {
+    case "":
+        ks.Encoding = EncodingDecimal
+    case EncodingDecimal, EncodingUint64BE:
+    case EncodingPadded:
+        if ks.Width <= 0 {
+            ks.Width = defaultPaddedWidth
+        }
+    default:
+        return ks, fmt.Errorf("unknown key encoding %q (want decimal, padded or uint64be)", ks.Encoding)
+    }
+    if ks.HashPrefix != "" && ks.HashPrefix == ks.Prefix {
+        return ks, fmt.Errorf("key schema hash_prefix must differ from prefix")
+    }
+    return ks, nil
+}
+
+func (ks KeySchema) twoLevel() bool {
+    return ks.HashPrefix != ""
+}
+
+func (ks KeySchema) heightKey(height int) []byte {
+    key := []byte(ks.Prefix)
+    switch ks.Encoding {
+    case EncodingPadded:
+        return append(key, fmt.Sprintf("%0*d", ks.Width, height)...)
+    case EncodingUint64BE:
+        return binary.BigEndian.AppendUint64(key, uint64(height))
+    default:
+        return strconv.AppendInt(key, int64(height), 10)
+    }
+}
+
+func (ks KeySchema) hashKey(hash []byte) []byte {
+    return append([]byte(ks.HashPrefix), hash...)
+}
+
+// parseHeight returns the height encoded in key, rejecting keys that share
+// the prefix but are not in this schema's exact format.
+func (ks KeySchema) parseHeight(key []byte) (int, bool) {
+    if len(key) < len(ks.Prefix) || string(key[:len(ks.Prefix)]) != ks.Prefix {
+        return 0, false
+    }
+    suffix := key[len(ks.Prefix):]
+
+    switch ks.Encoding {
+    case EncodingUint64BE:
+        if len(suffix) != 8 {
+            return 0, false
+        }
+        height := binary.BigEndian.Uint64(suffix)
+        if height > uint64(int(^uint(0)>>1)) {
+            return 0, false
+        }
+        return int(height), true
+    case EncodingPadded:
+        if len(suffix) != ks.Width {
+            return 0, false
+        }
+    default:
+        if len(suffix) == 0 || (len(suffix) > 1 && suffix[0] == '0') {
+            return 0, false
+        }
+    }
+
+    for _, c := range suffix {
+        if c < '0' || c > '9' {
+            return 0, false
+        }
+    }
+    height, err := strconv.Atoi(string(suffix))
+    if err != nil {
+        return 0, false
+    }
+    return height, true
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+    "testing"
+
+    "inspector/internal/blocks"
+)
+
+func TestMemorySourceIterate(t *testing.T) {
+    source, err := NewMemorySource([]*blocks.Block{
+        {Height: 0, Hash: "a", PrevHash: "0"},
+        {Height: 1, Hash: "b", PrevHash: "a"},
+        {Height: 3, Hash: "d", PrevHash: "c"},
+    })
+    if err != nil {
+        t.Fatalf("Failed to build source: %v", err)
+    }
+    source.PutRaw(2, []byte("{not json"))
+
+    var heights []int
+    decodeErrors := 0
+    err = source.IterateBlocks(0, 10, func(height int, block *blocks.Block, err error) bool {
+        heights = append(heights, height)
+        if err != nil {
+            decodeErrors++
+        }
+        return true
+    })
+    if err != nil {
+        t.Fatalf("IterateBlocks failed: %v", err)
+    }
+
+    if len(heights) != 4 || heights[0] != 0 || heights[3] != 3 {
+        t.Errorf("Expected heights [0 1 2 3], got %v", heights)
+    }
+    if decodeErrors != 1 {
+        t.Errorf("Expected 1 decode error, got %d", decodeErrors)
+    }
+}
+
+func TestMemorySourceTipIgnoresStrayHeights(t *testing.T) {
+    source, _ := NewMemorySource([]*blocks.Block{
+        {Height: -5, Hash: "x"},
+        {Height: 0, Hash: "a"},
+        {Height: 1, Hash: "b"},
+        {Height: 1 << 40, Hash: "z"},
+    })
+
+    tip, err := source.Tip()
+    if err != nil || tip.Highest != 1<<40 {
+        t.Errorf("Unexpected tip %+v (%v)", tip, err)
+    }
+}
+
+func TestMemorySourceSatisfiesBlockSource(t *testing.T) {
+    var source BlockSource
+    source, _ = NewMemorySource(nil)
+
+    tip, err := source.Tip()
+    if err != nil || tip.Highest != -1 {
+        t.Errorf("Expected empty tip, got %+v (%v)", tip, err)
+    }
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+)
+
+// checkDatabaseDir fails clearly when dbPath is missing or is not a LevelDB
+// directory, instead of letting an open create an empty database there.
+func checkDatabaseDir(dbPath string) error {
+    info, err := os.Stat(dbPath)
+    if os.IsNotExist(err) {
+        return fmt.Errorf("database %s does not exist", dbPath)
+    }
+    if err != nil {
+        return fmt.Errorf("failed to stat database: %w", err)
+    }
+    if !info.IsDir() {
+        return fmt.Errorf("database %s is not a directory", dbPath)
+    }
+    if _, err := os.Stat(filepath.Join(dbPath, "CURRENT")); err != nil {
+        return fmt.Errorf("%s is not a LevelDB database (no CURRENT file)", dbPath)
+    }
+    return nil
+}
+
+// snapshotDatabase copies the database files of dbPath into a new temporary
+// directory. LOCK and LOG are left behind; they carry no data.
+func snapshotDatabase(dbPath string) (string, error) {
+    entries, err := os.ReadDir(dbPath)
+    if err != nil {
+        return "", fmt.Errorf("failed to read database dir: %w", err)
+    }
+
+    tempDir, err := os.MkdirTemp("", "inspector-snapshot-")
+    if err != nil {
+        return "", fmt.Errorf("failed to create snapshot dir: %w", err)
+    }
+
+    for _, entry := range entries {
+        name := entry.Name()
+        if !entry.Type().IsRegular() || name == "LOCK" || name == "LOG" || name == "LOG.old" {
+            continue
+        }
+        err := copyFile(filepath.Join(dbPath, name), filepath.Join(tempDir, name))
+        // Compaction can delete a table between ReadDir and the copy.
+        if os.IsNotExist(err) {
+            continue
+        }
+        if err != nil {
+            os.RemoveAll(tempDir)
+            return "", fmt.Errorf("failed to snapshot %s: %w", name, err)
+        }
+    }
+    return tempDir, nil
+}
+
+func copyFile(src, dst string) error {
+    in, err := os.Open(src)
+    if err != nil {
+        return err
+    }
+    defer in.Close()
+
+    out, err := os.Create(dst)
+    if err != nil {
+        return err
+    }
+    if _, err := io.Copy(out, in); err != nil {
+        out.Close()
+        return err
+    }
+    return out.Close()
+}
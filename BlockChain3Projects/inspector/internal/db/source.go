@@ -0,0 +1,47 @@
+package db
+
+import (
+    "errors"
+
+    "inspector/internal/blocks"
+)
+
+// ErrBlockNotFound is returned by every BlockSource when no block is stored
+// at the requested height, so callers can tell gaps apart from read failures.
+var ErrBlockNotFound = errors.New("block not found")
+
+// BlockSource is anything the inspector can read a chain from: a LevelDB
+// directory, a live node over RPC, or blocks held in memory.
+type BlockSource interface {
+    LoadBlock(height int) (*blocks.Block, error)
+    LoadBlockRaw(height int) ([]byte, error)
+    Tip() (ChainTip, error)
+    IterateBlocks(from, to int, visit BlockVisitor) error
+    Close() error
+}
+
+// ChainTip describes where a chain ends. Highest is the largest stored
+// height, -1 for an empty chain. Where the chain first breaks is left to
+// callers that walk it anyway, since finding it means reading every key.
+type ChainTip struct {
+    Highest int `json:"highest"`
+}
+
+// BlockVisitor receives every block present in an iterated range, in height
+// order. err is set when a value exists but cannot be decoded. Returning
+// false stops the iteration.
+type BlockVisitor func(height int, block *blocks.Block, err error) bool
+
+// RawSource is implemented by sources that can hand out stored values
+// undecoded, so callers can spread decoding over several goroutines.
+// DecodeBlock decodes a value the way IterateBlocks would and is safe for
+// concurrent use.
+type RawSource interface {
+    IterateRaw(from, to int, visit RawVisitor) error
+    DecodeBlock(data []byte) (*blocks.Block, error)
+}
+
+// RawVisitor receives every stored value in an iterated range, in height
+// order. err is set when the value cannot be read. data stays valid
+// after visit returns but must not be modified.
+type RawVisitor func(height int, data []byte, err error) bool
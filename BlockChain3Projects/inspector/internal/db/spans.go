@@ -0,0 +1,73 @@
+package db
+
+import (
+    "strconv"
+)
+
+// keySpan is a key range to iterate. Only keys that parse to a height in
+// [lo, hi] belong to the span. A decimal span also contains every longer key
+// that shares a prefix with one of its heights; digits is set so the
+// iterator can seek past those instead of reading them.
+type keySpan struct {
+    start  []byte
+    limit  []byte
+    lo, hi int
+    digits int
+}
+
+// spans returns key ranges that, iterated in order, visit [from, to] in
+// height order. Padded and big-endian keys already sort numerically, so one
+// span is enough. Unpadded decimal keys sort as strings ("block-10" before
+// "block-9"), but heights of equal length sort numerically, so they get one
+// span per digit count, shortest first.
+func (ks KeySchema) spans(from, to int) []keySpan {
+    if ks.Encoding != EncodingDecimal {
+        return []keySpan{{
+            start: ks.heightKey(from),
+            limit: append(ks.heightKey(to), 0),
+            lo:    from,
+            hi:    to,
+        }}
+    }
+
+    spans := []keySpan{}
+    lower := 0
+    upper := 9
+    for lower <= to {
+        lo, hi := lower, upper
+        if from > lo {
+            lo = from
+        }
+        if to < hi {
+            hi = to
+        }
+        if lo <= hi {
+            spans = append(spans, keySpan{
+                start: ks.heightKey(lo),
+                limit: append(ks.heightKey(hi), 0),
+                lo:     lo,
+                hi:     hi,
+                digits: len(strconv.Itoa(lo)),
+            })
+        }
+        if upper > (int(^uint(0)>>1)-9)/10 {
+            break
+        }
+        lower = upper + 1
+        upper = upper*10 + 9
+    }
+    return spans
+}
+
+// skip returns the key to seek to when key is longer than the span's heights,
+// or nil if key should be read. "block-1" is followed by every "block-1<d>..."
+// key before "block-2", so seeking to "block-1:" (':' sorts after '9') jumps
+// over all of them and a full pass reads each stored key about once.
+func (ks KeySchema) skip(span keySpan, key []byte) []byte {
+    end := len(ks.Prefix) + span.digits
+    if span.digits == 0 || len(key) <= end || key[end] < '0' || key[end] > '9' {
+        return nil
+    }
+    next := append([]byte(nil), key[:end]...)
+    return append(next, '9'+1)
+}
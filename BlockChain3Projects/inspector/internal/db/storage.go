@@ -1,67 +1,293 @@
 package db
 
 import (
-    "encoding/json"
+    "errors"
     "fmt"
+    "os"
+    "path/filepath"
+    "sync"
 
     "inspector/internal/blocks"
+    "inspector/internal/codec"
     "github.com/syndtr/goleveldb/leveldb"
+    "github.com/syndtr/goleveldb/leveldb/iterator"
+    "github.com/syndtr/goleveldb/leveldb/opt"
+    "github.com/syndtr/goleveldb/leveldb/util"
 )
 
+// ErrReadOnly is returned when writing to a storage opened read-only.
+var ErrReadOnly = errors.New("database is opened read-only")
+
 type Storage struct {
-    db *leveldb.DB
+    db       *leveldb.DB
+    schema   KeySchema
+    codec    string
+    readOnly bool
+    tempDir  string
+
+    indexMu sync.Mutex
+    index   *HashIndex
 }
 
+// Options controls how a database directory is opened.
+type Options struct {
+    // ReadOnly never creates, locks exclusively, or rewrites anything in the
+    // directory, and fails if it does not hold a database.
+    ReadOnly bool
+    // Snapshot copies the database files to a temporary directory and opens
+    // the copy read-only, so a node that holds the LOCK can keep running.
+    Snapshot bool
+    // KeySchema selects the node's key layout; the zero value is BHIV's.
+    KeySchema KeySchema
+    // Codec forces a value encoding such as "cbor" or "snappy+json"; empty
+    // detects it per value. SaveBlock writes json when it is empty.
+    Codec string
+}
+
+// NewStorage opens dbPath for reading and writing, creating it if needed.
 func NewStorage(dbPath string) (*Storage, error) {
-    database, err := leveldb.OpenFile(dbPath, nil)
+    return OpenStorage(dbPath, Options{})
+}
+
+func OpenStorage(dbPath string, opts Options) (*Storage, error) {
+    schema, err := opts.KeySchema.normalized()
+    if err != nil {
+        return nil, err
+    }
+
+    if !opts.ReadOnly && !opts.Snapshot {
+        database, err := leveldb.OpenFile(dbPath, nil)
+        if err != nil {
+            return nil, fmt.Errorf("failed to open database: %w", err)
+        }
+        return &Storage{db: database, schema: schema, codec: opts.Codec}, nil
+    }
+
+    if err := checkDatabaseDir(dbPath); err != nil {
+        return nil, err
+    }
+
+    // goleveldb creates a missing LOCK file even in read-only mode, so such
+    // directories are only ever read through a snapshot.
+    if _, err := os.Stat(filepath.Join(dbPath, "LOCK")); os.IsNotExist(err) {
+        opts.Snapshot = true
+    }
+
+    openPath := dbPath
+    tempDir := ""
+    if opts.Snapshot {
+        dir, err := snapshotDatabase(dbPath)
+        if err != nil {
+            return nil, err
+        }
+        openPath, tempDir = dir, dir
+    }
+
+    database, err := leveldb.OpenFile(openPath, &opt.Options{ReadOnly: true, ErrorIfMissing: true})
     if err != nil {
-        return nil, fmt.Errorf("failed to open database: %w", err)
+        if tempDir != "" {
+            os.RemoveAll(tempDir)
+            return nil, fmt.Errorf("failed to open snapshot of %s: %w", dbPath, err)
+        }
+        return nil, fmt.Errorf("failed to open database read-only (a running node may hold its LOCK; try --snapshot): %w", err)
     }
-    return &Storage{db: database}, nil
+    return &Storage{db: database, schema: schema, codec: opts.Codec, readOnly: true, tempDir: tempDir}, nil
 }
 
 func (s *Storage) Close() error {
-    return s.db.Close()
+    err := s.db.Close()
+    if s.tempDir != "" {
+        os.RemoveAll(s.tempDir)
+    }
+    return err
 }
 
 func (s *Storage) LoadBlock(height int) (*blocks.Block, error) {
-    key := []byte(fmt.Sprintf("block-%d", height))
-    data, err := s.db.Get(key, nil)
+    data, err := s.LoadBlockRaw(height)
     if err != nil {
         return nil, err
     }
 
-    var block blocks.Block
-    if err := json.Unmarshal(data, &block); err != nil {
-        return nil, err
-    }
-    return &block, nil
+    block, _, err := codec.Decode(data, s.codec)
+    return block, err
 }
 
 func (s *Storage) LoadBlockRaw(height int) ([]byte, error) {
-    key := []byte(fmt.Sprintf("block-%d", height))
-    return s.db.Get(key, nil)
+    data, err := s.db.Get(s.schema.heightKey(height), nil)
+    if err == leveldb.ErrNotFound {
+        return nil, ErrBlockNotFound
+    }
+    if err != nil || !s.schema.twoLevel() {
+        return data, err
+    }
+    return s.loadByHash(s.db, height, data)
+}
+
+// loadByHash follows a two-level layout's height index entry to the block.
+func (s *Storage) loadByHash(reader leveldb.Reader, height int, hash []byte) ([]byte, error) {
+    data, err := reader.Get(s.schema.hashKey(hash), nil)
+    if err == leveldb.ErrNotFound {
+        return nil, fmt.Errorf("height %d indexes hash %x but no block is stored under it", height, hash)
+    }
+    return data, err
+}
+
+// IterateBlocks streams the blocks in [from, to] from a consistent snapshot
+// using LevelDB iterators rather than a Get per height.
+func (s *Storage) IterateBlocks(from, to int, visit BlockVisitor) error {
+    return s.IterateRaw(from, to, func(height int, data []byte, err error) bool {
+        if err != nil {
+            return visit(height, nil, err)
+        }
+        block, err := s.DecodeBlock(data)
+        return visit(height, block, err)
+    })
+}
+
+func (s *Storage) DecodeBlock(data []byte) (*blocks.Block, error) {
+    block, _, err := codec.Decode(data, s.codec)
+    return block, err
+}
+
+// IterateRaw is IterateBlocks without the decoding.
+func (s *Storage) IterateRaw(from, to int, visit RawVisitor) error {
+    if from < 0 {
+        from = 0
+    }
+    snap, err := s.db.GetSnapshot()
+    if err != nil {
+        return err
+    }
+    defer snap.Release()
+
+    return s.walkKeys(snap, from, to, func(height int, iter iterator.Iterator) bool {
+        // The iterator reuses its value buffer.
+        value := append([]byte(nil), iter.Value()...)
+        var err error
+        if s.schema.twoLevel() {
+            value, err = s.loadByHash(snap, height, value)
+        }
+        return visit(height, value, err)
+    })
+}
+
+// walkKeys calls visit with the iterator positioned on each height key in
+// [from, to], in height order, until visit returns false.
+func (s *Storage) walkKeys(reader leveldb.Reader, from, to int, visit func(height int, iter iterator.Iterator) bool) error {
+    for _, span := range s.schema.spans(from, to) {
+        iter := reader.NewIterator(&util.Range{Start: span.start, Limit: span.limit}, nil)
+        more := iter.First()
+        for more {
+            key := iter.Key()
+            if next := s.schema.skip(span, key); next != nil {
+                more = iter.Seek(next)
+                continue
+            }
+
+            height, ok := s.schema.parseHeight(key)
+            if ok && height >= span.lo && height <= span.hi && !visit(height, iter) {
+                iter.Release()
+                return nil
+            }
+            more = iter.Next()
+        }
+        iter.Release()
+        if err := iter.Error(); err != nil {
+            return err
+        }
+    }
+    return nil
 }
 
 func (s *Storage) SaveBlock(block *blocks.Block) error {
-    key := []byte(fmt.Sprintf("block-%d", block.Height))
-    data, err := json.Marshal(block)
+    if s.readOnly {
+        return ErrReadOnly
+    }
+    s.indexMu.Lock()
+    s.index = nil
+    s.indexMu.Unlock()
+
+    data, err := codec.Encode(block, s.codec)
     if err != nil {
         return err
     }
-    return s.db.Put(key, data, nil)
+    if !s.schema.twoLevel() {
+        return s.db.Put(s.schema.heightKey(block.Height), data, nil)
+    }
+
+    batch := new(leveldb.Batch)
+    batch.Put(s.schema.heightKey(block.Height), []byte(block.Hash))
+    batch.Put(s.schema.hashKey([]byte(block.Hash)), data)
+    return s.db.Write(batch, nil)
 }
 
-func (s *Storage) GetMaxHeight() int {
-    height := 0
-    for {
-        _, err := s.LoadBlock(height)
+// HashIndex builds the hash index on first use and caches it for the life
+// of the storage. Writes through SaveBlock drop the cached copy.
+func (s *Storage) HashIndex() (*HashIndex, error) {
+    s.indexMu.Lock()
+    defer s.indexMu.Unlock()
+
+    if s.index == nil {
+        index, err := BuildHashIndex(s)
         if err != nil {
-            if height == 0 {
-                return -1
+            return nil, err
+        }
+        s.index = index
+    }
+    return s.index, nil
+}
+
+// GetMaxHeight returns the highest stored height, or -1 for an empty or
+// unreadable database.
+func (s *Storage) GetMaxHeight() int {
+    tip, err := s.Tip()
+    if err != nil {
+        return -1
+    }
+    return tip.Highest
+}
+
+// Tip seeks to the highest height key, so gaps do not hide blocks stored
+// beyond them. No value is read or decoded.
+func (s *Storage) Tip() (ChainTip, error) {
+    highest, err := s.highest(s.db)
+    if err != nil {
+        return ChainTip{Highest: -1}, err
+    }
+    return ChainTip{Highest: highest}, nil
+}
+
+// highest returns the largest stored height, or -1 if there is none. It
+// walks the spans backwards from the longest keys, and within a decimal span
+// seeks from a longer key back to its prefix, so it reads a handful of keys
+// rather than the whole range.
+func (s *Storage) highest(reader leveldb.Reader) (int, error) {
+    spans := s.schema.spans(0, int(^uint(0)>>1))
+    for i := len(spans) - 1; i >= 0; i-- {
+        span := spans[i]
+        iter := reader.NewIterator(&util.Range{Start: span.start, Limit: span.limit}, nil)
+        more := iter.Last()
+        for more {
+            if next := s.schema.skip(span, iter.Key()); next != nil {
+                // The prefix sorts before everything it extends.
+                prefix := next[:len(next)-1]
+                if more = iter.Seek(prefix); more && string(iter.Key()) != string(prefix) {
+                    more = iter.Prev()
+                }
+                continue
+            }
+
+            height, ok := s.schema.parseHeight(iter.Key())
+            if ok && height >= span.lo && height <= span.hi {
+                iter.Release()
+                return height, nil
             }
-            return height - 1
+            more = iter.Prev()
+        }
+        iter.Release()
+        if err := iter.Error(); err != nil {
+            return -1, err
         }
-        height++
     }
+    return -1, nil
 }
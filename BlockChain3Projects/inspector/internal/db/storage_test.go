@@ -1,6 +1,7 @@
 package db
 
 import (
+    "fmt"
     "os"
     "testing"
     
@@ -60,3 +61,180 @@ func TestSaveAndLoadBlock(t *testing.T) {
         t.Errorf("Expected hash %s, got %s", block.Hash, loadedBlock.Hash)
     }
 }
+
+func TestReadOnlyRejectsMissingPath(t *testing.T) {
+    testPath := "./test_db_missing"
+    defer os.RemoveAll(testPath)
+    
+    _, err := OpenStorage(testPath, Options{ReadOnly: true})
+    if err == nil {
+        t.Fatal("Expected error opening a missing database read-only")
+    }
+    
+    if _, statErr := os.Stat(testPath); !os.IsNotExist(statErr) {
+        t.Error("Read-only open must not create the directory")
+    }
+}
+
+func TestSnapshotWhileLocked(t *testing.T) {
+    testPath := "./test_db_locked"
+    defer os.RemoveAll(testPath)
+    
+    writer, err := NewStorage(testPath)
+    if err != nil {
+        t.Fatalf("Failed to create storage: %v", err)
+    }
+    defer writer.Close()
+    writer.SaveBlock(&blocks.Block{Height: 0, Hash: "genesis"})
+    
+    if _, err := OpenStorage(testPath, Options{ReadOnly: true}); err == nil {
+        t.Error("Expected read-only open to fail while the LOCK is held")
+    }
+    
+    reader, err := OpenStorage(testPath, Options{Snapshot: true})
+    if err != nil {
+        t.Fatalf("Snapshot open failed: %v", err)
+    }
+    defer reader.Close()
+    
+    block, err := reader.LoadBlock(0)
+    if err != nil || block.Hash != "genesis" {
+        t.Errorf("Expected genesis block from snapshot, got %v (%v)", block, err)
+    }
+    if err := reader.SaveBlock(&blocks.Block{Height: 1}); err != ErrReadOnly {
+        t.Errorf("Expected ErrReadOnly, got %v", err)
+    }
+}
+
+func TestIterateBlocksInHeightOrder(t *testing.T) {
+    testPath := "./test_db_iterate"
+    defer os.RemoveAll(testPath)
+    
+    storage, err := NewStorage(testPath)
+    if err != nil {
+        t.Fatalf("Failed to create storage: %v", err)
+    }
+    defer storage.Close()
+    
+    for height := 0; height <= 120; height++ {
+        storage.SaveBlock(&blocks.Block{Height: height, Hash: "h"})
+    }
+    
+    var heights []int
+    err = storage.IterateBlocks(8, 101, func(height int, block *blocks.Block, err error) bool {
+        heights = append(heights, height)
+        return err == nil && block.Height == height
+    })
+    if err != nil {
+        t.Fatalf("IterateBlocks failed: %v", err)
+    }
+    
+    if len(heights) != 94 {
+        t.Fatalf("Expected 94 blocks, got %d", len(heights))
+    }
+    for i, height := range heights {
+        if height != 8+i {
+            t.Fatalf("Expected height %d at position %d, got %d", 8+i, i, height)
+        }
+    }
+}
+
+func TestDecimalSpansSkipLongerKeys(t *testing.T) {
+    schema, _ := KeySchema{}.normalized()
+    spans := schema.spans(5, 1200)
+    if len(spans) != 4 || spans[0].digits != 1 || spans[3].digits != 4 {
+        t.Fatalf("Unexpected spans %+v", spans)
+    }
+    
+    cases := map[string]string{
+        "block-7":    "",
+        "block-7x":   "",
+        "block-705":  "block-7:",
+        "block-1200": "block-1:",
+    }
+    for key, want := range cases {
+        if got := schema.skip(spans[0], []byte(key)); string(got) != want {
+            t.Errorf("skip(%q) = %q, want %q", key, got, want)
+        }
+    }
+    if got := schema.skip(spans[3], []byte("block-1200")); got != nil {
+        t.Errorf("Expected a four-digit key to be read, got seek to %q", got)
+    }
+}
+
+func TestKeySchemas(t *testing.T) {
+    schemas := map[string]KeySchema{
+        "decimal":   {},
+        "padded":    {Prefix: "blk:", Encoding: EncodingPadded, Width: 12},
+        "uint64be":  {Prefix: "b", Encoding: EncodingUint64BE},
+        "two-level": {Prefix: "height/", Encoding: EncodingUint64BE, HashPrefix: "hash/"},
+    }
+    
+    for name, schema := range schemas {
+        t.Run(name, func(t *testing.T) {
+            testPath := "./test_db_schema_" + name
+            defer os.RemoveAll(testPath)
+            
+            storage, err := OpenStorage(testPath, Options{KeySchema: schema})
+            if err != nil {
+                t.Fatalf("Failed to create storage: %v", err)
+            }
+            defer storage.Close()
+            
+            for _, height := range []int{0, 1, 2, 9, 10, 11, 300} {
+                block := &blocks.Block{Height: height, Hash: fmt.Sprintf("hash%d", height)}
+                if err := storage.SaveBlock(block); err != nil {
+                    t.Fatalf("Failed to save block %d: %v", height, err)
+                }
+            }
+            
+            tip, err := storage.Tip()
+            if err != nil || tip.Highest != 300 {
+                t.Errorf("Unexpected tip %+v (%v)", tip, err)
+            }
+            
+            block, err := storage.LoadBlock(10)
+            if err != nil || block.Hash != "hash10" {
+                t.Errorf("Expected hash10, got %v (%v)", block, err)
+            }
+            
+            var heights []int
+            storage.IterateBlocks(1, 300, func(height int, block *blocks.Block, err error) bool {
+                if err != nil {
+                    t.Errorf("Decode error at %d: %v", height, err)
+                }
+                heights = append(heights, height)
+                return true
+            })
+            if fmt.Sprint(heights) != "[1 2 9 10 11 300]" {
+                t.Errorf("Unexpected iteration order %v", heights)
+            }
+        })
+    }
+}
+
+func TestTipSeesPastGaps(t *testing.T) {
+    testPath := "./test_db_tip"
+    defer os.RemoveAll(testPath)
+    
+    storage, err := NewStorage(testPath)
+    if err != nil {
+        t.Fatalf("Failed to create storage: %v", err)
+    }
+    defer storage.Close()
+    
+    for _, height := range []int{0, 1, 2, 3, 9, 10, 120, 1200} {
+        storage.SaveBlock(&blocks.Block{Height: height, Hash: "h"})
+    }
+    storage.db.Put([]byte("block-99999999999999999999999"), []byte("h"), nil)
+    storage.db.Put([]byte("block--5"), []byte("h"), nil)
+    
+    tip, err := storage.Tip()
+    if err != nil {
+        t.Fatalf("Tip failed: %v", err)
+    }
+    
+    if tip.Highest != 1200 {
+        t.Errorf("Expected highest 1200, got %d", tip.Highest)
+    }
+}
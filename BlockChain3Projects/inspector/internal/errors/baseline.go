@@ -0,0 +1,129 @@
+package errors
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "time"
+
+    "inspector/internal/db"
+    "inspector/internal/rules"
+)
+
+// Baseline lists findings that are known and accepted, so scans only
+// report what is new. An entry matches a finding with the same rule,
+// height and block hash; a block that changed since is no longer covered.
+type Baseline struct {
+    Created  string          `json:"created"`
+    Database string          `json:"database"`
+    Entries  []BaselineEntry `json:"entries"`
+}
+
+type BaselineEntry struct {
+    RuleID    string `json:"rule_id"`
+    Height    int    `json:"height"`
+    BlockHash string `json:"block_hash,omitempty"`
+}
+
+// BaselineInfo tells how a baseline applied to a scan. Resolved counts
+// entries at heights the scan judged that no longer match any finding; Stale
+// describes entries whose block hash changed.
+type BaselineInfo struct {
+    Path       string   `json:"path"`
+    Entries    int      `json:"entries"`
+    Suppressed int      `json:"suppressed"`
+    Resolved   int      `json:"resolved"`
+    Stale      []string `json:"stale,omitempty"`
+}
+
+// NewBaseline accepts every finding of a scan, including those an applied
+// baseline already suppressed.
+func NewBaseline(result *ErrorScanResult) *Baseline {
+    baseline := &Baseline{
+        Created:  time.Now().Format("2006-01-02 15:04:05"),
+        Database: result.DatabasePath,
+        Entries:  []BaselineEntry{},
+    }
+    seen := make(map[BaselineEntry]bool)
+    for _, findings := range [][]rules.Finding{result.Suppressed, result.Findings} {
+        for _, f := range findings {
+            entry := baselineEntry(f)
+            if !seen[entry] {
+                seen[entry] = true
+                baseline.Entries = append(baseline.Entries, entry)
+            }
+        }
+    }
+    return baseline
+}
+
+func baselineEntry(f rules.Finding) BaselineEntry {
+    return BaselineEntry{RuleID: f.RuleID, Height: f.Height, BlockHash: f.BlockHash}
+}
+
+func LoadBaseline(path string) (*Baseline, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read baseline: %w", err)
+    }
+    var baseline Baseline
+    if err := json.Unmarshal(data, &baseline); err != nil {
+        return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+    }
+    return &baseline, nil
+}
+
+func SaveBaseline(path string, baseline *Baseline) error {
+    data, err := json.MarshalIndent(baseline, "", "  ")
+    if err != nil {
+        return err
+    }
+    return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// applyBaseline moves the findings the baseline accepts to Suppressed and
+// warns about entries for blocks that have since changed.
+func (s *scan) applyBaseline(storage db.BlockSource, baseline *Baseline, path string) {
+    result := s.result
+    info := &BaselineInfo{Path: path, Entries: len(baseline.Entries)}
+    result.Baseline = info
+
+    accepted := make(map[BaselineEntry]bool, len(baseline.Entries))
+    for _, entry := range baseline.Entries {
+        accepted[entry] = true
+    }
+    matched := make(map[BaselineEntry]bool)
+    kept := []rules.Finding{}
+    for _, f := range result.Findings {
+        entry := baselineEntry(f)
+        if accepted[entry] {
+            matched[entry] = true
+            result.Suppressed = append(result.Suppressed, f)
+            continue
+        }
+        kept = append(kept, f)
+    }
+    result.Findings = kept
+    info.Suppressed = len(result.Suppressed)
+    for entry := range accepted {
+        if !matched[entry] && s.covers(entry.Height) {
+            info.Resolved++
+        }
+    }
+
+    checked := make(map[int]bool)
+    for _, entry := range baseline.Entries {
+        if entry.BlockHash == "" || checked[entry.Height] {
+            continue
+        }
+        checked[entry.Height] = true
+        block, err := storage.LoadBlock(entry.Height)
+        switch {
+        case err != nil:
+            info.Stale = append(info.Stale, fmt.Sprintf("block %d accepted as %s can no longer be read: %v", entry.Height, entry.BlockHash, err))
+        case block.Hash != entry.BlockHash:
+            info.Stale = append(info.Stale, fmt.Sprintf("block %d accepted as %s is now %s", entry.Height, entry.BlockHash, block.Hash))
+        }
+    }
+    s.tally()
+}
@@ -0,0 +1,201 @@
+package errors
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "os"
+    "sort"
+
+    "inspector/internal/blocks"
+    "inspector/internal/db"
+    "inspector/internal/health"
+    "inspector/internal/rules"
+)
+
+// ruleSetRevision is bumped whenever a built-in rule or the checkpoint
+// changes what it records, so checkpoints taken before are not trusted.
+const ruleSetRevision = 3
+
+// ScanCheckpoint records how far a chain has been verified: every height up
+// to Height was present and decoded, and checked under the rule set
+// RuleSet names. CumulativeWork is the work of the blocks up to Height.
+// Findings are those reported up to Height, baseline or not, which an
+// incremental scan reports again without checking those blocks.
+type ScanCheckpoint struct {
+    Height         int             `json:"height"`
+    Hash           string          `json:"hash"`
+    RuleSet        string          `json:"rule_set"`
+    CumulativeWork string          `json:"cumulative_work"`
+    ScanTime       string          `json:"scan_time"`
+    Findings       []rules.Finding `json:"findings,omitempty"`
+}
+
+// CheckpointInfo tells how a scan used its checkpoint file. Height is the
+// checkpoint the scan wrote (-1 if none), Carried the findings a resumed
+// scan took over from it, Note explains a fall back to a full scan and
+// Error is set when the checkpoint could not be written.
+type CheckpointInfo struct {
+    Path    string `json:"path"`
+    Resumed bool   `json:"resumed"`
+    Height  int    `json:"height"`
+    Carried int    `json:"carried"`
+    Note    string `json:"note,omitempty"`
+    Error   string `json:"error,omitempty"`
+}
+
+// LoadCheckpoint reads a checkpoint file; a missing file gives nil.
+func LoadCheckpoint(path string) (*ScanCheckpoint, error) {
+    data, err := os.ReadFile(path)
+    if os.IsNotExist(err) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+    }
+    var cp ScanCheckpoint
+    if err := json.Unmarshal(data, &cp); err != nil {
+        return nil, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+    }
+    return &cp, nil
+}
+
+func SaveCheckpoint(path string, cp *ScanCheckpoint) error {
+    data, err := json.MarshalIndent(cp, "", "  ")
+    if err != nil {
+        return err
+    }
+    return os.WriteFile(path, data, 0644)
+}
+
+// ruleSetVersion fingerprints everything that decides what a scan reports:
+// the rule revision, the enabled rules and the chain settings.
+func ruleSetVersion(opts ScanOptions, ruleIDs []string) string {
+    ids := append([]string(nil), ruleIDs...)
+    sort.Strings(ids)
+    // The health model and fail_on only judge findings, so they do not count.
+    policy := *opts.policy()
+    policy.Health, policy.FailOn = health.Model{}, ""
+    settings := struct {
+        Rules         []string    `json:"rules"`
+        HashVersion   int         `json:"hash_version"`
+        HashAlgorithm string      `json:"hash_algorithm"`
+        Validators    interface{} `json:"validators"`
+        Pow           interface{} `json:"pow"`
+        Spec          interface{} `json:"spec"`
+        Policy        interface{} `json:"policy"`
+    }{ids, opts.HashVersion, opts.algorithm(), nil, opts.Pow, opts.Spec, policy}
+    if opts.Validators != nil {
+        settings.Validators = opts.Validators.Validators
+    }
+    data, _ := json.Marshal(settings)
+    sum := sha256.Sum256(data)
+    return fmt.Sprintf("r%d-%s", ruleSetRevision, hex.EncodeToString(sum[:8]))
+}
+
+// resume decides where an incremental scan starts. It returns the
+// checkpointed block to continue from, or nil for a full scan, recording
+// why in info. A checkpoint block that changed is reported as a finding.
+func (s *scan) resume(storage db.BlockSource, cp *ScanCheckpoint, ruleSet string, info *CheckpointInfo) *rules.Entry {
+    switch {
+    case cp == nil:
+        info.Note = "no checkpoint yet, full scan"
+        return nil
+    case cp.RuleSet != ruleSet:
+        info.Note = fmt.Sprintf("rule set changed (%s, now %s), full scan", cp.RuleSet, ruleSet)
+        return nil
+    }
+
+    block, err := storage.LoadBlock(cp.Height)
+    actual := ""
+    if err == nil {
+        actual = block.Hash
+    }
+    if actual != cp.Hash {
+        if rule := s.enabled[rules.CheckpointChanged]; rule != nil {
+            message := "Block changed since the last scan (reorg or tampering)"
+            if err != nil {
+                message = fmt.Sprintf("Block unreadable since the last scan - %v", err)
+            }
+            s.report(rule, []rules.Finding{{Height: cp.Height, BlockHash: actual, Expected: cp.Hash, Actual: actual, Message: message}})
+        }
+        info.Note = fmt.Sprintf("checkpoint block %d changed, full scan", cp.Height)
+        return nil
+    }
+
+    if _, ok := s.work.SetString(cp.CumulativeWork, 10); !ok {
+        s.work.SetInt64(0)
+        info.Note = "checkpoint has no cumulative work, full scan"
+        return nil
+    }
+    info.Resumed = true
+    s.carried = cp.Findings
+    return &rules.Entry{Height: cp.Height, Block: block}
+}
+
+// seed shows the Lookback rules the blocks below from, so their windows
+// hold what a full scan's would. Findings on those blocks are dropped: a
+// resumed scan carries them from its checkpoint, and a range or sample does
+// not cover them. Gaps among them are the rules' to handle, as in a full
+// scan.
+func (s *scan) seed(storage db.BlockSource, from int) error {
+    var seeded []rules.Lookback
+    depth := 0
+    for _, rule := range s.chainRules {
+        if r, ok := rule.(rules.Lookback); ok {
+            seeded = append(seeded, r)
+            if n := r.Lookback(s.ctx); n > depth {
+                depth = n
+            }
+        }
+    }
+    start := from - depth
+    if start < 0 {
+        start = 0
+    }
+    if depth == 0 || start >= from {
+        return nil
+    }
+    var prev *rules.Entry
+    return storage.IterateBlocks(start, from-1, func(height int, block *blocks.Block, err error) bool {
+        if err != nil {
+            prev = nil
+            return true
+        }
+        entry := rules.Entry{Height: height, Block: block}
+        for _, rule := range seeded {
+            rule.CheckPair(s.ctx, prev, entry)
+        }
+        prev = &entry
+        return true
+    })
+}
+
+// carry reports again the checkpoint's findings, those of the blocks an
+// incremental scan does not recheck. A finding the scan reported afresh,
+// such as a timestamp cluster running on past the checkpoint, is kept once.
+func (s *scan) carry() {
+    reported := make(map[string]bool)
+    for _, f := range s.result.Findings {
+        reported[fmt.Sprintf("%s@%d", f.RuleID, f.Height)] = true
+    }
+    for _, f := range s.carried {
+        if !reported[fmt.Sprintf("%s@%d", f.RuleID, f.Height)] {
+            s.result.Findings = append(s.result.Findings, f)
+            s.result.Checkpoint.Carried++
+        }
+    }
+}
+
+// outstanding is what the next checkpoint records: the findings up to the
+// verified height, before the baseline takes any out.
+func outstanding(findings []rules.Finding, height int) []rules.Finding {
+    var kept []rules.Finding
+    for _, f := range findings {
+        if f.Height <= height {
+            kept = append(kept, f)
+        }
+    }
+    return kept
+}
@@ -1,64 +1,134 @@
 package errors
 
 import (
+    "crypto/sha256"
+    "encoding/hex"
     "fmt"
+    "sort"
     "time"
 
+    "inspector/internal/blocks"
+    "inspector/internal/chainspec"
     "inspector/internal/db"
+    "inspector/internal/rules"
 )
 
 type ComparisonResult struct {
-    ScanTime            string   `json:"scan_time"`
-    Node1Path           string   `json:"node1_path"`
-    Node2Path           string   `json:"node2_path"`
-    Node1Height         int      `json:"node1_height"`
-    Node2Height         int      `json:"node2_height"`
-    MatchingBlocks      int      `json:"matching_blocks"`
-    MismatchedBlocks    []int    `json:"mismatched_blocks"`
-    Node1OnlyBlocks     []int    `json:"node1_only_blocks"`
-    Node2OnlyBlocks     []int    `json:"node2_only_blocks"`
-    DivergencePoint     int      `json:"divergence_point"`
-    HashMismatches      []string `json:"hash_mismatches"`
-    DataMismatches      []string `json:"data_mismatches"`
-    TimestampMismatches []string `json:"timestamp_mismatches"`
-    SyncPercentage      float64  `json:"sync_percentage"`
-    Recommendations     []string `json:"recommendations"`
+    ScanTime           string          `json:"scan_time"`
+    Node1Path          string          `json:"node1_path"`
+    Node2Path          string          `json:"node2_path"`
+    Node1Height        int             `json:"node1_height"`
+    Node2Height        int             `json:"node2_height"`
+    Node1HashAlgorithm string          `json:"node1_hash_algorithm"`
+    Node2HashAlgorithm string          `json:"node2_hash_algorithm"`
+    DifferentChains    bool            `json:"different_chains"`
+    MatchingBlocks     int             `json:"matching_blocks"`
+    DivergencePoint    int             `json:"divergence_point"`
+    SyncPercentage     float64         `json:"sync_percentage"`
+    Counts             map[string]int  `json:"counts"`
+    Findings           []rules.Finding `json:"findings"`
+    Recommendations    []string        `json:"recommendations"`
 }
 
-func CompareNodes(storage1, storage2 *db.Storage, db1Path, db2Path string) *ComparisonResult {
+// Finding ids of a comparison. In findings about both nodes Expected holds
+// node1's value and Actual node2's; bad_hash, chain_spec and missing_on_node
+// findings name the one node they concern.
+const (
+    HashMismatch      = "hash_mismatch"
+    DataMismatch      = "data_mismatch"
+    TimestampMismatch = "timestamp_mismatch"
+    MissingOnNode     = "missing_on_node"
+)
+
+var comparisonSeverity = map[string]rules.Severity{
+    "bad_hash":        rules.SeverityCritical,
+    "chain_spec":      rules.SeverityCritical,
+    HashMismatch:      rules.SeverityError,
+    DataMismatch:      rules.SeverityError,
+    TimestampMismatch: rules.SeverityWarning,
+    MissingOnNode:     rules.SeverityWarning,
+}
+
+// NodeCount counts the findings of a rule that concern node ("node1" or
+// "node2").
+func (r *ComparisonResult) NodeCount(ruleID, node string) int {
+    n := 0
+    for _, f := range r.Findings {
+        if f.RuleID == ruleID && f.Node == node {
+            n++
+        }
+    }
+    return n
+}
+
+func (r *ComparisonResult) add(f rules.Finding) {
+    f.Severity = comparisonSeverity[f.RuleID]
+    r.Findings = append(r.Findings, f)
+}
+
+func CompareNodes(storage1, storage2 db.BlockSource, db1Path, db2Path string) *ComparisonResult {
+    return CompareNodesWithOptions(storage1, storage2, db1Path, db2Path, ScanOptions{}, ScanOptions{})
+}
+
+// CompareNodesWithOptions compares two chains, verifying each node's block
+// hashes under that node's own hash scheme so a mismatch can be pinned on
+// the node holding the invalid block.
+func CompareNodesWithOptions(storage1, storage2 db.BlockSource, db1Path, db2Path string, opts1, opts2 ScanOptions) *ComparisonResult {
     result := &ComparisonResult{
-        ScanTime:        time.Now().Format("2006-01-02 15:04:05"),
-        Node1Path:       db1Path,
-        Node2Path:       db2Path,
-        DivergencePoint: -1,
+        ScanTime:           time.Now().Format("2006-01-02 15:04:05"),
+        Node1Path:          db1Path,
+        Node2Path:          db2Path,
+        Node1HashAlgorithm: opts1.algorithm(),
+        Node2HashAlgorithm: opts2.algorithm(),
+        DivergencePoint:    -1,
+        Findings:           []rules.Finding{},
     }
 
-    result.Node1Height = storage1.GetMaxHeight()
-    result.Node2Height = storage2.GetMaxHeight()
+    tip1, _ := storage1.Tip()
+    tip2, _ := storage2.Tip()
+    result.Node1Height = tip1.Highest
+    result.Node2Height = tip2.Highest
 
     maxHeight := result.Node1Height
     if result.Node2Height > maxHeight {
         maxHeight = result.Node2Height
     }
 
-    for i := 0; i <= maxHeight; i++ {
-        block1, err1 := storage1.LoadBlock(i)
-        block2, err2 := storage2.LoadBlock(i)
+    digests1, err := collectDigests(storage1, maxHeight, opts1)
+    if err != nil {
+        result.Recommendations = []string{fmt.Sprintf("Node1 could not be read: %v", err)}
+        return result
+    }
+    digests2, err := collectDigests(storage2, maxHeight, opts2)
+    if err != nil {
+        result.Recommendations = []string{fmt.Sprintf("Node2 could not be read: %v", err)}
+        return result
+    }
 
-        if err1 != nil && err2 != nil {
-            continue
-        }
+    wrong1 := checkSpec(result, "node1", opts1.Spec, digests1, result.Node1Height)
+    wrong2 := checkSpec(result, "node2", opts2.Spec, digests2, result.Node2Height)
+    result.DifferentChains = wrong1 || wrong2
+    if digests1[0] != nil && digests2[0] != nil && digests1[0].Hash != digests2[0].Hash {
+        result.DifferentChains = true
+    }
 
-        if err1 != nil && err2 == nil {
-            result.Node2OnlyBlocks = append(result.Node2OnlyBlocks, i)
-            if result.DivergencePoint == -1 {
-                result.DivergencePoint = i
+    for _, i := range digestHeights(digests1, digests2) {
+        block1, block2 := digests1[i], digests2[i]
+
+        for node, block := range []*blockDigest{block1, block2} {
+            if block != nil && !block.Valid {
+                result.add(rules.Finding{RuleID: "bad_hash", Height: i, Node: fmt.Sprintf("node%d", node+1), BlockHash: block.Hash,
+                    Expected: block.Computed, Actual: block.Hash, Message: "Bad hash"})
             }
-            continue
         }
 
-        if err1 == nil && err2 != nil {
-            result.Node1OnlyBlocks = append(result.Node1OnlyBlocks, i)
+        if block1 == nil || block2 == nil {
+            node, present := "node1", block2
+            if block2 == nil {
+                node, present = "node2", block1
+            }
+            result.add(rules.Finding{RuleID: MissingOnNode, Height: i, Node: node, BlockHash: present.Hash,
+                Message: fmt.Sprintf("Block not on %s", node)})
             if result.DivergencePoint == -1 {
                 result.DivergencePoint = i
             }
@@ -66,39 +136,129 @@ func CompareNodes(storage1, storage2 *db.Storage, db1Path, db2Path string) *Comp
         }
 
         if block1.Hash != block2.Hash {
-            result.MismatchedBlocks = append(result.MismatchedBlocks, i)
             if result.DivergencePoint == -1 {
                 result.DivergencePoint = i
             }
-            errMsg := fmt.Sprintf("Block %d: Hash mismatch", i)
-            result.HashMismatches = append(result.HashMismatches, errMsg)
+            result.add(rules.Finding{RuleID: HashMismatch, Height: i, Expected: block1.Hash, Actual: block2.Hash,
+                Message: fmt.Sprintf("Hash mismatch (node1 %s, node2 %s)", validity(block1.Valid), validity(block2.Valid))})
         } else {
             result.MatchingBlocks++
         }
 
         if block1.Data != block2.Data {
-            errMsg := fmt.Sprintf("Block %d: Data differs", i)
-            result.DataMismatches = append(result.DataMismatches, errMsg)
+            result.add(rules.Finding{RuleID: DataMismatch, Height: i, BlockHash: block1.Hash,
+                Expected: hex.EncodeToString(block1.Data[:]), Actual: hex.EncodeToString(block2.Data[:]),
+                Message: "Data differs (sha256 of data)"})
         }
 
         if block1.Timestamp != block2.Timestamp {
-            errMsg := fmt.Sprintf("Block %d: Timestamp differs", i)
-            result.TimestampMismatches = append(result.TimestampMismatches, errMsg)
+            result.add(rules.Finding{RuleID: TimestampMismatch, Height: i, BlockHash: block1.Hash,
+                Expected: fmt.Sprint(block1.Timestamp), Actual: fmt.Sprint(block2.Timestamp),
+                Message: "Timestamp differs"})
         }
     }
 
     if maxHeight >= 0 {
         result.SyncPercentage = (float64(result.MatchingBlocks) / float64(maxHeight+1)) * 100
     }
+    sort.SliceStable(result.Findings, func(i, j int) bool { return result.Findings[i].Height < result.Findings[j].Height })
+    result.Counts = rules.Count(result.Findings)
 
     result.Recommendations = generateRecommendations(result)
 
     return result
 }
 
+// blockDigest holds what CompareNodes needs of a block, so whole chains can
+// be read in one pass without keeping their data in memory. Valid records
+// whether the stored hash verifies under the node's hash scheme, and
+// Computed the hash it should have.
+type blockDigest struct {
+    Hash      string
+    Computed  string
+    Data      [sha256.Size]byte
+    Timestamp int64
+    Valid     bool
+}
+
+// collectDigests reads heights 0..maxHeight from source in a single
+// sequential pass. Missing or undecodable blocks have no entry, so a stray
+// far-off height costs one entry rather than a slot per height below it.
+func collectDigests(source db.BlockSource, maxHeight int, opts ScanOptions) (map[int]*blockDigest, error) {
+    digests := make(map[int]*blockDigest)
+    err := source.IterateBlocks(0, maxHeight, func(height int, block *blocks.Block, err error) bool {
+        if err == nil {
+            computed, hashErr := blocks.ComputeBlockHashWith(block, block.EffectiveHashVersion(opts.HashVersion), opts.HashAlgorithm)
+            digests[height] = &blockDigest{
+                Hash:      block.Hash,
+                Computed:  computed,
+                Data:      sha256.Sum256([]byte(block.Data)),
+                Timestamp: block.Timestamp,
+                Valid:     hashErr == nil && computed == block.Hash,
+            }
+        }
+        return true
+    })
+    return digests, err
+}
+
+// digestHeights returns the heights present on either node, in order.
+func digestHeights(digests1, digests2 map[int]*blockDigest) []int {
+    heights := make([]int, 0, len(digests1))
+    for height := range digests1 {
+        heights = append(heights, height)
+    }
+    for height := range digests2 {
+        if digests1[height] == nil {
+            heights = append(heights, height)
+        }
+    }
+    sort.Ints(heights)
+    return heights
+}
+
+// checkSpec holds a node's genesis and checkpoints against its chain spec,
+// adding a finding per mismatch. It reports whether the genesis itself does
+// not match.
+func checkSpec(result *ComparisonResult, node string, spec *chainspec.Spec, digests map[int]*blockDigest, tip int) bool {
+    if spec == nil {
+        return false
+    }
+
+    mismatches := spec.CheckHashes(tip, func(height int) (string, bool) {
+        if digests[height] == nil {
+            return "", false
+        }
+        return digests[height].Hash, true
+    })
+
+    for _, f := range rules.SpecFindings(mismatches) {
+        f.RuleID, f.Node = "chain_spec", node
+        result.add(f)
+    }
+    return chainspec.WrongChain(mismatches)
+}
+
+func validity(valid bool) string {
+    if valid {
+        return "valid"
+    }
+    return "invalid"
+}
+
 func generateRecommendations(result *ComparisonResult) []string {
     recs := []string{}
 
+    if result.DifferentChains {
+        recs = append(recs, "Nodes hold different chains (genesis differs) - check that both belong to the same network")
+    }
+    if n := result.NodeCount("chain_spec", "node1"); n > 0 {
+        recs = append(recs, fmt.Sprintf("Node1 disagrees with the chain spec at %d genesis/checkpoint block(s)", n))
+    }
+    if n := result.NodeCount("chain_spec", "node2"); n > 0 {
+        recs = append(recs, fmt.Sprintf("Node2 disagrees with the chain spec at %d genesis/checkpoint block(s)", n))
+    }
+
     heightDiff := result.Node1Height - result.Node2Height
     if heightDiff > 0 {
         recs = append(recs, fmt.Sprintf("Node2 is %d blocks behind - sync from Node1", heightDiff))
@@ -110,6 +270,13 @@ func generateRecommendations(result *ComparisonResult) []string {
         recs = append(recs, fmt.Sprintf("Chains diverge at block %d", result.DivergencePoint))
     }
 
+    if n := result.NodeCount("bad_hash", "node1"); n > 0 {
+        recs = append(recs, fmt.Sprintf("Node1 has %d blocks whose hash does not verify under %s - check its data or hash_algorithm", n, result.Node1HashAlgorithm))
+    }
+    if n := result.NodeCount("bad_hash", "node2"); n > 0 {
+        recs = append(recs, fmt.Sprintf("Node2 has %d blocks whose hash does not verify under %s - check its data or hash_algorithm", n, result.Node2HashAlgorithm))
+    }
+
     if len(recs) == 0 {
         recs = append(recs, "Nodes are perfectly synchronized")
     }
@@ -4,7 +4,11 @@ import (
     "encoding/json"
     "fmt"
     "os"
+    "sort"
     "strings"
+    "time"
+
+    "inspector/internal/rules"
 )
 
 // DAY 1: CENTRALIZED ERROR HANDLING [file:15]
@@ -60,25 +64,83 @@ func outputScanText(result *ErrorScanResult) {
     fmt.Println(strings.Repeat("═", 66))
     fmt.Printf("\n📊 STATISTICS:\n")
     fmt.Printf("  Blocks Scanned:   %d\n", result.BlocksScanned)
-    fmt.Printf("  Total Errors:     %d\n", result.TotalErrors)
-    fmt.Printf("  Health Score:     %d%%\n", result.HealthScore)
+    if c := result.Coverage; c.Mode == "incremental" {
+        fmt.Printf("  Coverage:         incremental, %d new heights (%.1f%% of chain)\n", c.Heights, c.Percent)
+    } else if c.Partial {
+        fmt.Printf("  Coverage:         %s of %d..%d, %d heights (%.1f%% of chain)\n", c.Mode, c.From, c.To, c.Heights, c.Percent)
+        if c.SampleMode == "random" {
+            fmt.Printf("  Sample:           random, seed %d (--seed to repeat)\n", c.Seed)
+        } else if c.SampleMode != "" {
+            fmt.Printf("  Sample:           %s\n", c.SampleMode)
+        }
+    }
+    if cp := result.Checkpoint; cp != nil {
+        if cp.Resumed {
+            fmt.Printf("  Resumed From:     block %d (checkpoint %s)\n", result.Coverage.From, cp.Path)
+        } else if cp.Note != "" {
+            fmt.Printf("  Checkpoint:       %s\n", cp.Note)
+        }
+        if cp.Error != "" {
+            fmt.Printf("  Checkpoint Error: %s\n", cp.Error)
+        } else if cp.Height >= 0 {
+            fmt.Printf("  Verified To:      block %d\n", cp.Height)
+        }
+    }
+    if result.ContiguousHeight >= 0 {
+        fmt.Printf("  Tip Height:       %d (contiguous to %d)\n", result.TipHeight, result.ContiguousHeight)
+    } else {
+        fmt.Printf("  Tip Height:       %d\n", result.TipHeight)
+    }
+    fmt.Printf("  Hash Scheme:      %s, v%d for blocks without hash_version\n", result.HashAlgorithm, result.HashVersion)
+    if result.ChainID != "" {
+        fmt.Printf("  Chain ID:         %s\n", result.ChainID)
+    }
+    printPolicy(result.Policy)
+    fmt.Printf("  Total Findings:   %d\n", result.TotalFindings)
+    fmt.Printf("  Total Errors:     %d (%s and above)\n", result.TotalErrors, result.Policy.FailOn)
+    fmt.Printf("  Cumulative Work:  %s\n", result.CumulativeWork)
+    grade := ""
+    if result.Health != nil {
+        grade = " " + result.Health.Grade
+    }
+    if c := result.Coverage; c.Mode == "incremental" && result.Checkpoint != nil {
+        fmt.Printf("  Health Score:     %d%%%s (%d new heights checked, %d findings carried from the checkpoint)\n",
+            result.HealthScore, grade, c.Heights, result.Checkpoint.Carried)
+    } else if result.Coverage.Partial {
+        fmt.Printf("  Health Score:     %d%%%s (partial coverage: %d of %d heights, %.1f%%)\n",
+            result.HealthScore, grade, result.Coverage.Heights, result.TipHeight+1, result.Coverage.Percent)
+    } else {
+        fmt.Printf("  Health Score:     %d%%%s\n", result.HealthScore, grade)
+    }
     fmt.Printf("  Status:           %s\n", result.Status)
     
     fmt.Println("\n🔍 ERROR CLASSIFICATION:")
-    fmt.Printf("  Corrupted JSON:           %d\n", len(result.CorruptedJSON))
-    fmt.Printf("  Bad Hash:                 %d\n", len(result.BadHash))
-    fmt.Printf("  Timestamp Future:         %d\n", len(result.TimestampFuture))
-    fmt.Printf("  Timestamp Past:           %d\n", len(result.TimestampPast))
-    fmt.Printf("  Timestamp Not Increasing: %d\n", len(result.TimestampNotIncreasing))
-    fmt.Printf("  Duplicate Hashes:         %d\n", len(result.DuplicateHashes))
-    fmt.Printf("  Empty Blocks:             %d\n", len(result.EmptyBlocks))
-    fmt.Printf("  PrevHash Errors:          %d\n", len(result.PrevHashErrors))
-    fmt.Printf("  Height Errors:            %d\n", len(result.HeightErrors))
-    fmt.Printf("  Missing Blocks:           %d\n", len(result.MissingBlocks))
-    fmt.Printf("  Out of Order:             %d\n", len(result.OutOfOrderBlocks))
+    for _, id := range result.Rules {
+        fmt.Printf("  %-26s %d\n", id+":", result.Counts[id])
+    }
+    if result.Health != nil && len(result.Health.Breakdown) > 0 {
+        fmt.Printf("\n🩺 HEALTH BREAKDOWN (%d of %d blocks affected, each charged for its worst finding):\n",
+            result.Health.Affected, result.Health.Units)
+        for _, line := range result.Health.Explain() {
+            fmt.Printf("  %s\n", line)
+        }
+    }
+    printFindings("FINDINGS", result.Findings)
+    if b := result.Baseline; b != nil {
+        fmt.Printf("\n🔕 BASELINE %s:\n", b.Path)
+        fmt.Printf("  Accepted Findings: %d\n", b.Entries)
+        fmt.Printf("  Suppressed:        %d\n", b.Suppressed)
+        fmt.Printf("  Resolved:          %d (accepted, no longer found)\n", b.Resolved)
+        for _, stale := range b.Stale {
+            fmt.Printf("  ⚠️  Stale baseline: %s\n", stale)
+        }
+        printFindings("SUPPRESSED FINDINGS", result.Suppressed)
+    }
     
-    if result.TotalErrors == 0 {
+    if result.TotalFindings == 0 {
         fmt.Println("\n🎉 No errors found! Blockchain is healthy.")
+    } else if result.TotalErrors == 0 {
+        fmt.Printf("\n✅ Healthy: %d finding(s), none %s or above.\n", result.TotalFindings, result.Policy.FailOn)
     } else {
         fmt.Println("\n⚠️  Errors detected.")
     }
@@ -90,17 +152,25 @@ func outputComparisonText(result *ComparisonResult) {
     fmt.Println("NODE COMPARISON SUMMARY")
     fmt.Println(strings.Repeat("═", 66))
     fmt.Printf("\n📊 NODE INFO:\n")
-    fmt.Printf("  Node1: %s (Height: %d)\n", result.Node1Path, result.Node1Height)
-    fmt.Printf("  Node2: %s (Height: %d)\n", result.Node2Path, result.Node2Height)
+    fmt.Printf("  Node1: %s (Height: %d, %s)\n", result.Node1Path, result.Node1Height, result.Node1HashAlgorithm)
+    fmt.Printf("  Node2: %s (Height: %d, %s)\n", result.Node2Path, result.Node2Height, result.Node2HashAlgorithm)
     
+    if result.DifferentChains {
+        fmt.Println("\n⛔ DIFFERENT CHAINS: the nodes' genesis blocks do not match")
+    }
+
     fmt.Println("\n🔍 RESULTS:")
     fmt.Printf("  Matching Blocks:    %d\n", result.MatchingBlocks)
-    fmt.Printf("  Mismatched Blocks:  %d\n", len(result.MismatchedBlocks))
+    fmt.Printf("  Mismatched Blocks:  %d\n", result.Counts[HashMismatch])
+    fmt.Printf("  Missing Blocks:     %d / %d (node1 / node2)\n", result.NodeCount(MissingOnNode, "node1"), result.NodeCount(MissingOnNode, "node2"))
+    fmt.Printf("  Invalid Hashes:     %d / %d (node1 / node2)\n", result.NodeCount("bad_hash", "node1"), result.NodeCount("bad_hash", "node2"))
+    fmt.Printf("  Chain Spec Errors:  %d / %d (node1 / node2)\n", result.NodeCount("chain_spec", "node1"), result.NodeCount("chain_spec", "node2"))
     fmt.Printf("  Sync Percentage:    %.1f%%\n", result.SyncPercentage)
     
     if result.DivergencePoint >= 0 {
         fmt.Printf("\n🔀 Divergence Point: Block %d\n", result.DivergencePoint)
     }
+    printFindings("FINDINGS", result.Findings)
     
     fmt.Println("\n🔧 RECOMMENDATIONS:")
     for i, rec := range result.Recommendations {
@@ -108,3 +178,44 @@ func outputComparisonText(result *ComparisonResult) {
     }
     fmt.Println(strings.Repeat("═", 66))
 }
+
+// maxTextFindings caps the findings listed in text output; --json has all.
+const maxTextFindings = 50
+
+func printPolicy(policy rules.Policy) {
+    empty := "reported"
+    if policy.AllowEmptyBlocks {
+        empty = "allowed"
+    }
+    fmt.Printf("  Policy:           clock skew %ds, timestamps from %s, empty blocks %s\n",
+        policy.ClockSkew, time.Unix(policy.MinTimestamp, 0).UTC().Format("2006-01-02"), empty)
+    ts := policy.Timestamps
+    fmt.Printf("  Timestamps:       median of %d, %s outliers beyond %g, clusters of %d under %g of the interval\n",
+        ts.MedianWindow, ts.IntervalMethod, ts.IntervalThreshold, ts.ClusterBlocks, ts.ClusterFraction)
+    ids := make([]string, 0, len(policy.Severity))
+    for id := range policy.Severity {
+        ids = append(ids, id)
+    }
+    sort.Strings(ids)
+    for _, id := range ids {
+        fmt.Printf("  Severity:         %s is %s\n", id, policy.Severity[id])
+    }
+}
+
+func printFindings(title string, findings []rules.Finding) {
+    if len(findings) == 0 {
+        return
+    }
+    fmt.Printf("\n📋 %s:\n", title)
+    for i, f := range findings {
+        if i == maxTextFindings {
+            fmt.Printf("  ... and %d more (use --json for all)\n", len(findings)-i)
+            break
+        }
+        rule := f.RuleID
+        if f.Node != "" {
+            rule += "@" + f.Node
+        }
+        fmt.Printf("  %-10s %-26s %s\n", "["+string(f.Severity)+"]", rule, f)
+    }
+}
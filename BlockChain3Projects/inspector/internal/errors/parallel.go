@@ -0,0 +1,75 @@
+package errors
+
+import (
+    "sync"
+
+    "inspector/internal/blocks"
+    "inspector/internal/db"
+)
+
+// runParallel reads the chain on one goroutine, checks blocks on workers
+// goroutines and consumes them in height order on the calling one. Sources
+// that hand out raw values have them decoded by the workers too. At most
+// window items are in flight, so one slow block holds the readers back
+// instead of letting finished items pile up.
+func (s *scan) runParallel(source db.BlockSource, from, to, workers int) error {
+    raw, _ := source.(db.RawSource)
+    if raw != nil {
+        s.decode = raw.DecodeBlock
+    }
+    window := workers * 16
+    jobs := make(chan *scanItem, window)
+    done := make(chan *scanItem, window)
+    slots := make(chan struct{}, window)
+
+    var wg sync.WaitGroup
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for item := range jobs {
+                s.check(item)
+                done <- item
+            }
+        }()
+    }
+
+    var iterErr error
+    go func() {
+        defer close(jobs)
+        seq := 0
+        send := func(item *scanItem) bool {
+            slots <- struct{}{}
+            item.seq = seq
+            seq++
+            jobs <- item
+            return true
+        }
+        if raw != nil {
+            iterErr = raw.IterateRaw(from, to, func(i int, data []byte, err error) bool {
+                return send(&scanItem{height: i, raw: data, err: err})
+            })
+        } else {
+            iterErr = source.IterateBlocks(from, to, func(i int, block *blocks.Block, err error) bool {
+                return send(&scanItem{height: i, block: block, err: err})
+            })
+        }
+    }()
+    go func() {
+        wg.Wait()
+        close(done)
+    }()
+
+    pending := make(map[int]*scanItem)
+    nextSeq := 0
+    for item := range done {
+        pending[item.seq] = item
+        for ready := pending[nextSeq]; ready != nil; ready = pending[nextSeq] {
+            delete(pending, nextSeq)
+            s.consume(ready)
+            <-slots
+            nextSeq++
+        }
+    }
+    return iterErr
+}
@@ -0,0 +1,219 @@
+package errors
+
+import (
+    "errors"
+    "fmt"
+    "math/rand"
+    "sort"
+    "time"
+
+    "inspector/internal/blocks"
+    "inspector/internal/db"
+    "inspector/internal/rules"
+)
+
+// ScanRange limits a scan to heights From..To, To -1 meaning the tip.
+// Sample, when above 0, checks only that many heights of the range, picked
+// by SampleMode "stride" (evenly spaced) or "random"; a random sample with
+// Seed 0 gets a fresh seed, reported in the coverage so it can be replayed.
+type ScanRange struct {
+    From       int
+    To         int
+    Sample     int
+    SampleMode string
+    Seed       int64
+}
+
+// Coverage tells which heights a scan checked: all of them ("full"),
+// those after a checkpoint ("incremental"), a window ("range") or a
+// "sample". Heights counts the heights checked and Percent their share of
+// the chain.
+type Coverage struct {
+    Mode       string  `json:"mode"`
+    Partial    bool    `json:"partial"`
+    From       int     `json:"from"`
+    To         int     `json:"to"`
+    Heights    int     `json:"heights"`
+    Percent    float64 `json:"percent"`
+    SampleMode string  `json:"sample_mode,omitempty"`
+    Seed       int64   `json:"seed,omitempty"`
+}
+
+// fullCoverage is the coverage of a scan from from to the tip. A scan
+// resumed after a checkpoint does not recheck the blocks below it and is
+// partial.
+func fullCoverage(from, tip int) Coverage {
+    c := Coverage{Mode: "full", From: from, To: tip, Heights: tip - from + 1, Percent: 100}
+    if c.Heights < 0 {
+        c.Heights = 0
+    }
+    if from > 0 {
+        c.Mode, c.Partial = "incremental", true
+        c.Percent = float64(c.Heights) * 100 / float64(tip+1)
+    }
+    return c
+}
+
+// runPartial scans a range of the chain or a sample of it. Chain rules see
+// the block stored just below each checked stretch, so linkage holds at
+// the edges, and Lookback rules the window below it; each sample gets fresh
+// Lookback rules, as its neighbours are not the samples before it. Gaps
+// are only reported inside the heights checked.
+func (s *scan) runPartial(storage db.BlockSource, r ScanRange, workers int) error {
+    tip := s.ctx.Tip.Highest
+    s.ctx.Partial = true
+    if r.To < 0 || r.To > tip {
+        r.To = tip
+    }
+    if r.From < 0 || r.From > r.To {
+        return fmt.Errorf("empty scan range %d..%d (tip %d)", r.From, r.To, tip)
+    }
+
+    coverage := Coverage{Mode: "range", Partial: true, From: r.From, To: r.To, Heights: r.To - r.From + 1}
+    if r.Sample > 0 {
+        if r.SampleMode == "" {
+            r.SampleMode = "random"
+        }
+        if r.SampleMode == "random" && r.Seed == 0 {
+            r.Seed = time.Now().UnixNano()
+        }
+        heights, err := sampleHeights(r)
+        if err != nil {
+            return err
+        }
+        coverage.Mode, coverage.SampleMode, coverage.Heights = "sample", r.SampleMode, len(heights)
+        if r.SampleMode == "random" {
+            coverage.Seed = r.Seed
+        }
+        s.sampled = make(map[int]bool, len(heights))
+        for _, height := range heights {
+            s.sampled[height] = true
+            s.prev = s.loadEntry(storage, height-1)
+            s.next = height
+            block, err := storage.LoadBlock(height)
+            if errors.Is(err, db.ErrBlockNotFound) {
+                s.markMissing(height, height)
+                continue
+            }
+            s.renew()
+            if err := s.seed(storage, height); err != nil {
+                return err
+            }
+            item := &scanItem{height: height, block: block, err: err}
+            s.check(item)
+            s.consume(item)
+        }
+        s.next = r.To + 1
+    } else {
+        s.prev = s.loadEntry(storage, r.From-1)
+        s.next = r.From
+        if err := s.seed(storage, r.From); err != nil {
+            return err
+        }
+        var err error
+        if workers > 1 {
+            err = s.runParallel(storage, r.From, r.To, workers)
+        } else {
+            err = storage.IterateBlocks(r.From, r.To, func(i int, block *blocks.Block, err error) bool {
+                item := &scanItem{height: i, block: block, err: err}
+                s.check(item)
+                s.consume(item)
+                return true
+            })
+        }
+        if err != nil {
+            return fmt.Errorf("scan interrupted at block %d: %w", s.next, err)
+        }
+    }
+
+    coverage.Percent = float64(coverage.Heights) * 100 / float64(tip+1)
+    s.result.Coverage = coverage
+    s.finish(r.To)
+    return nil
+}
+
+// renew swaps every Lookback rule for a fresh instance. A run the old one
+// was still holding is reported first.
+func (s *scan) renew() {
+    for i, rule := range s.chainRules {
+        if _, ok := rule.(rules.Lookback); !ok {
+            continue
+        }
+        fresh, ok := rules.New(rule.ID()).(rules.ChainRule)
+        if !ok {
+            continue
+        }
+        if r, ok := rule.(rules.Finisher); ok {
+            s.report(r, r.Finish(s.ctx))
+        }
+        s.chainRules[i], s.enabled[rule.ID()] = fresh, fresh
+        for j, f := range s.finishers {
+            if f.ID() == rule.ID() {
+                s.finishers[j] = fresh.(rules.Finisher)
+            }
+        }
+    }
+}
+
+// covers reports whether the scan judged height: a sample only the heights
+// it picked, an incremental scan every height to the tip, those below the
+// checkpoint through the findings it carries.
+func (s *scan) covers(height int) bool {
+    c := s.result.Coverage
+    switch {
+    case s.sampled != nil:
+        return s.sampled[height]
+    case c.Mode == "incremental":
+        return height >= 0 && height <= c.To
+    }
+    return height >= c.From && height <= c.To
+}
+
+// loadEntry reads the block at height for use as a previous block, or
+// returns nil when there is none to read.
+func (s *scan) loadEntry(storage db.BlockSource, height int) *rules.Entry {
+    if height < 0 {
+        return nil
+    }
+    block, err := storage.LoadBlock(height)
+    if err != nil {
+        return nil
+    }
+    return &rules.Entry{Height: height, Block: block}
+}
+
+// sampleHeights picks r.Sample distinct heights of r.From..r.To in
+// ascending order.
+func sampleHeights(r ScanRange) ([]int, error) {
+    span := r.To - r.From + 1
+    n := r.Sample
+    if n > span {
+        n = span
+    }
+    heights := make([]int, 0, n)
+
+    switch r.SampleMode {
+    case "stride":
+        for k := 0; k < n; k++ {
+            heights = append(heights, r.From+k*span/n)
+        }
+    case "random":
+        // Floyd's algorithm: n distinct picks without a span-sized array.
+        rng := rand.New(rand.NewSource(r.Seed))
+        chosen := make(map[int]bool, n)
+        for j := span - n; j < span; j++ {
+            if t := rng.Intn(j + 1); chosen[t] {
+                chosen[j] = true
+            } else {
+                chosen[t] = true
+            }
+        }
+        for offset := range chosen {
+            heights = append(heights, r.From+offset)
+        }
+        sort.Ints(heights)
+    default:
+        return nil, fmt.Errorf("unknown sample mode %q (stride, random)", r.SampleMode)
+    }
+    return heights, nil
+}
@@ -1,43 +1,158 @@
 package errors
 
 import (
-    "encoding/json"
+    "errors"
     "fmt"
-    "strings"
+    "math/big"
+    "sort"
     "time"
 
     "inspector/internal/blocks"
+    "inspector/internal/chainspec"
+    "inspector/internal/codec"
     "inspector/internal/db"
+    "inspector/internal/health"
+    "inspector/internal/rules"
+    "inspector/internal/validators"
 )
 
 type ErrorScanResult struct {
-    ScanTime                string   `json:"scan_time"`
-    DatabasePath            string   `json:"database_path"`
-    TotalBlocks             int      `json:"total_blocks"`
-    BlocksScanned           int      `json:"blocks_scanned"`
-    TotalErrors             int      `json:"total_errors"`
-    CorruptedJSON           []string `json:"corrupted_json"`
-    BadHash                 []string `json:"bad_hash"`
-    TimestampFuture         []string `json:"timestamp_future"`
-    TimestampPast           []string `json:"timestamp_past"`
-    TimestampNotIncreasing  []string `json:"timestamp_not_increasing"`
-    DuplicateHashes         []string `json:"duplicate_hashes"`
-    EmptyBlocks             []string `json:"empty_blocks"`
-    PrevHashErrors          []string `json:"prevhash_errors"`
-    HeightErrors            []string `json:"height_errors"`
-    MissingBlocks           []int    `json:"missing_blocks"`
-    OutOfOrderBlocks        []string `json:"out_of_order_blocks"`
-    HealthScore             int      `json:"health_score"`
-    Status                  string   `json:"status"`
-}
-
-func ScanErrors(storage *db.Storage, dbPath string) *ErrorScanResult {
+    ScanTime         string              `json:"scan_time"`
+    DatabasePath     string              `json:"database_path"`
+    HashVersion      int                 `json:"hash_version"`
+    HashAlgorithm    string              `json:"hash_algorithm"`
+    ChainID          string              `json:"chain_id,omitempty"`
+    TotalBlocks      int                 `json:"total_blocks"`
+    TipHeight        int                 `json:"tip_height"`
+    ContiguousHeight int                 `json:"contiguous_height"`
+    BlocksScanned    int                 `json:"blocks_scanned"`
+    TotalFindings    int                 `json:"total_findings"`
+    TotalErrors      int                 `json:"total_errors"`
+    Coverage         Coverage            `json:"coverage"`
+    Policy           rules.Policy        `json:"policy"`
+    Checkpoint       *CheckpointInfo     `json:"checkpoint,omitempty"`
+    Rules            []string            `json:"rules"`
+    Counts           map[string]int      `json:"counts"`
+    Findings         []rules.Finding     `json:"findings"`
+    Baseline         *BaselineInfo       `json:"baseline,omitempty"`
+    Suppressed       []rules.Finding     `json:"suppressed,omitempty"`
+    CumulativeWork   string              `json:"cumulative_work"`
+    HealthScore      int                 `json:"health_score"`
+    Health           *health.Score       `json:"health,omitempty"`
+    Status           string              `json:"status"`
+}
+
+// FindingsFor returns the findings of one rule.
+func (r *ErrorScanResult) FindingsFor(ruleID string) []rules.Finding {
+    var findings []rules.Finding
+    for _, f := range r.Findings {
+        if f.RuleID == ruleID {
+            findings = append(findings, f)
+        }
+    }
+    return findings
+}
+
+// ScanOptions carries chain settings the scan cannot read off the blocks.
+// HashVersion is the scheme assumed for blocks without a hash_version field;
+// HashAlgorithm names a blocks hash algorithm ("" for sha256). With a
+// validator set every block must be signed by a validator active at its
+// height; without one, signatures present are still checked. Pow adds the
+// difficulty and retarget rules; hashes are always checked against the
+// difficulty a block declares. Spec pins the genesis block and checkpoints.
+// EnabledRules, when set, limits the scan to those rule ids; DisabledRules
+// are left out either way. Workers above 1 decode blocks and run the block
+// rules on that many goroutines; the result is the same as a sequential
+// scan's. With a Checkpoint path the scan records how far the chain
+// verified; Incremental scans then start after that block, unless the block
+// changed or the rule set differs, and report the findings below it from
+// the checkpoint. Windowed rules are shown the blocks they look back over;
+// rules needing the whole chain (duplicate_hash, orphan_block,
+// side_chain_block) only see the blocks since the checkpoint. Range makes
+// the scan partial; partial scans leave the checkpoint alone. Policy
+// sets the rules' thresholds and severities (default rules.DefaultPolicy).
+// Findings the Baseline accepts are moved to Suppressed and do not count
+// against the health score or status. TotalErrors counts the findings at
+// or above the policy's fail_on severity; only those fail the scan.
+type ScanOptions struct {
+    HashVersion   int
+    HashAlgorithm string
+    Validators    *validators.Set
+    Pow           *blocks.PowParams
+    Spec          *chainspec.Spec
+    EnabledRules  []string
+    DisabledRules []string
+    Workers       int
+    Checkpoint    string
+    Incremental   bool
+    Range         *ScanRange
+    Policy        *rules.Policy
+    Baseline      *Baseline
+    BaselinePath  string
+}
+
+// algorithm is the hash algorithm name with the default filled in.
+func (o ScanOptions) algorithm() string {
+    if o.HashAlgorithm == "" {
+        return blocks.DefaultHashAlgorithm
+    }
+    return o.HashAlgorithm
+}
+
+// policy is the scan policy with the default filled in.
+func (o ScanOptions) policy() *rules.Policy {
+    if o.Policy == nil {
+        return rules.DefaultPolicy()
+    }
+    return o.Policy
+}
+
+func ScanErrors(storage db.BlockSource, dbPath string) *ErrorScanResult {
+    return ScanErrorsWithOptions(storage, dbPath, ScanOptions{})
+}
+
+// ScanErrorsWithOptions runs the selected rules over every stored block.
+// Findings come out in height order; Counts holds the number per rule.
+func ScanErrorsWithOptions(storage db.BlockSource, dbPath string, opts ScanOptions) *ErrorScanResult {
     result := &ErrorScanResult{
-        ScanTime:     time.Now().Format("2006-01-02 15:04:05"),
-        DatabasePath: dbPath,
+        ScanTime:      time.Now().Format("2006-01-02 15:04:05"),
+        DatabasePath:  dbPath,
+        HashVersion:   opts.HashVersion,
+        HashAlgorithm: opts.algorithm(),
+        Rules:         []string{},
+        Counts:        make(map[string]int),
+        Findings:      []rules.Finding{},
+    }
+    if opts.Spec != nil {
+        result.ChainID = opts.Spec.ChainID
+    }
+
+    if _, err := blocks.HashAlgorithm(opts.HashAlgorithm); err != nil {
+        result.Status = fmt.Sprintf("ERROR: %v", err)
+        return result
+    }
+    selected, err := rules.Select(opts.EnabledRules, opts.DisabledRules)
+    if err != nil {
+        result.Status = fmt.Sprintf("ERROR: %v", err)
+        return result
+    }
+    policy := opts.policy()
+    if err := policy.Validate(); err != nil {
+        result.Status = fmt.Sprintf("ERROR: %v", err)
+        return result
+    }
+    now := time.Now().Unix()
+    result.Policy = policy.Resolve(now)
+
+    tip, err := storage.Tip()
+    if err != nil {
+        result.Status = fmt.Sprintf("ERROR: %v", err)
+        return result
     }
+    result.TipHeight = tip.Highest
+    result.ContiguousHeight = -1
 
-    height := storage.GetMaxHeight()
+    height := tip.Highest
     if height < 0 {
         result.Status = "ERROR: Empty database"
         result.HealthScore = 0
@@ -45,116 +160,293 @@ func ScanErrors(storage *db.Storage, dbPath string) *ErrorScanResult {
     }
 
     result.TotalBlocks = height + 1
-    seenHashes := make(map[string]int)
-    var prevBlock *blocks.Block
-    expectedHeight := 0
-    currentTime := time.Now().Unix()
-
-    for i := 0; i <= height+10; i++ {
-        rawData, rawErr := storage.LoadBlockRaw(i)
-        
-        if rawErr != nil {
-            if i <= height {
-                result.MissingBlocks = append(result.MissingBlocks, i)
-                result.TotalErrors++
-            }
-            if i > height {
-                break
+    s := newScan(result, selected, &rules.Context{
+        HashVersion:   opts.HashVersion,
+        HashAlgorithm: opts.HashAlgorithm,
+        Validators:    opts.Validators,
+        Pow:           opts.Pow,
+        Spec:          opts.Spec,
+        Now:           now,
+        Policy:        result.Policy,
+        Tip:           tip,
+    })
+
+    if opts.Range != nil {
+        if err := s.runPartial(storage, *opts.Range, opts.Workers); err != nil {
+            result.Status = fmt.Sprintf("ERROR: %v", err)
+            return result
+        }
+        if opts.Baseline != nil {
+            s.applyBaseline(storage, opts.Baseline, opts.BaselinePath)
+        }
+        setStatus(result)
+        return result
+    }
+
+    from := 0
+    ruleSet := ruleSetVersion(opts, result.Rules)
+    if opts.Checkpoint != "" {
+        result.Checkpoint = &CheckpointInfo{Path: opts.Checkpoint, Height: -1}
+    }
+    if opts.Checkpoint != "" && opts.Incremental {
+        cp, err := LoadCheckpoint(opts.Checkpoint)
+        if err != nil {
+            result.Checkpoint.Note = fmt.Sprintf("%v, full scan", err)
+        } else if start := s.resume(storage, cp, ruleSet, result.Checkpoint); start != nil {
+            from = start.Height + 1
+            s.prev, s.verified, s.next = start, start, from
+            s.verifiedWork.Set(s.work)
+            s.ctx.Partial = true
+            if err := s.seed(storage, from); err != nil {
+                result.Status = fmt.Sprintf("ERROR: %v", err)
+                return result
             }
-            continue
         }
+    }
+    result.Coverage = fullCoverage(from, height)
 
-        var block blocks.Block
-        err := json.Unmarshal(rawData, &block)
+    var iterErr error
+    switch {
+    case from > height:
+        // Nothing stored since the checkpoint.
+    case opts.Workers > 1:
+        iterErr = s.runParallel(storage, from, height, opts.Workers)
+    default:
+        iterErr = storage.IterateBlocks(from, height, func(i int, block *blocks.Block, err error) bool {
+            item := &scanItem{height: i, block: block, err: err}
+            s.check(item)
+            s.consume(item)
+            return true
+        })
+    }
+    if iterErr != nil {
+        result.Status = fmt.Sprintf("ERROR: scan interrupted at block %d: %v", s.next, iterErr)
+        return result
+    }
+    s.finish(height)
+    // The chain runs unbroken up to the first gap found; there is none below
+    // an incremental scan's checkpoint.
+    result.ContiguousHeight = height
+    if len(s.ctx.Missing) > 0 {
+        result.ContiguousHeight = s.ctx.Missing[0].From - 1
+    }
+    var recorded []rules.Finding
+    if s.verified != nil {
+        recorded = outstanding(result.Findings, s.verified.Height)
+    }
+    if opts.Baseline != nil {
+        s.applyBaseline(storage, opts.Baseline, opts.BaselinePath)
+    }
+    setStatus(result)
+    if result.Checkpoint != nil && s.verified != nil {
+        result.Checkpoint.Height = s.verified.Height
+        err := SaveCheckpoint(opts.Checkpoint, &ScanCheckpoint{
+            Height:         s.verified.Height,
+            Hash:           s.verified.Block.Hash,
+            RuleSet:        ruleSet,
+            CumulativeWork: s.verifiedWork.String(),
+            ScanTime:       result.ScanTime,
+            Findings:       recorded,
+        })
         if err != nil {
-            errMsg := fmt.Sprintf("Block %d: Corrupted JSON - %v", i, err)
-            result.CorruptedJSON = append(result.CorruptedJSON, errMsg)
-            result.TotalErrors++
-            continue
+            result.Checkpoint.Error = err.Error()
         }
+    }
+    return result
+}
 
-        result.BlocksScanned++
+// setStatus scores a finished scan with the policy's health model, each
+// height checked being a unit; an incremental scan carries the findings
+// below its checkpoint, so every height up to the tip is. Partial scans say
+// so in their status.
+func setStatus(result *ErrorScanResult) {
+    issues := make([]health.Issue, 0, len(result.Findings))
+    for _, f := range result.Findings {
+        issues = append(issues, health.Issue{Unit: f.Height, Span: f.Span, Category: f.RuleID, Severity: string(f.Severity)})
+    }
+    units := result.Coverage.Heights
+    if result.Coverage.Mode == "incremental" {
+        units = result.Coverage.To + 1
+    }
+    if units < result.BlocksScanned {
+        units = result.BlocksScanned
+    }
+    score := result.Policy.Health.Score(units, issues)
+    result.Health = &score
+    result.HealthScore = int(score.Score)
 
-        computedHash := blocks.ComputeHash(block.Height, block.PrevHash, block.Data, block.Timestamp)
-        if block.Hash != computedHash {
-            errMsg := fmt.Sprintf("Block %d: Bad hash", i)
-            result.BadHash = append(result.BadHash, errMsg)
-            result.TotalErrors++
-        }
+    if result.TotalErrors == 0 {
+        result.Status = "HEALTHY"
+    } else {
+        result.Status = "ERRORS_FOUND"
+    }
+    if result.Coverage.Partial {
+        result.Status += " (partial coverage)"
+    }
+}
 
-        if firstHeight, exists := seenHashes[block.Hash]; exists {
-            errMsg := fmt.Sprintf("Block %d duplicates hash from Block %d", i, firstHeight)
-            result.DuplicateHashes = append(result.DuplicateHashes, errMsg)
-            result.TotalErrors++
-        } else {
-            seenHashes[block.Hash] = i
-        }
+// scan is the state of one ScanErrorsWithOptions run. check may run on
+// several goroutines; consume and finish run on one, in height order.
+type scan struct {
+    result       *ErrorScanResult
+    ctx          *rules.Context
+    enabled      map[string]rules.Rule
+    blockRules   []rules.BlockRule
+    chainRules   []rules.ChainRule
+    finishers    []rules.Finisher
+    decode       func(data []byte) (*blocks.Block, error)
+    prev         *rules.Entry
+    verified     *rules.Entry
+    verifiedWork *big.Int
+    carried      []rules.Finding
+    sampled      map[int]bool
+    broken       bool
+    next         int
+    work         *big.Int
+}
 
-        if block.Timestamp > currentTime+300 {
-            errMsg := fmt.Sprintf("Block %d: Timestamp in future", i)
-            result.TimestampFuture = append(result.TimestampFuture, errMsg)
-            result.TotalErrors++
-        }
+// scanItem is one stored value on its way through the scan. raw holds the
+// value while it still has to be decoded; seq is its place in the scan.
+type scanItem struct {
+    seq      int
+    height   int
+    raw      []byte
+    block    *blocks.Block
+    err      error
+    findings []rules.Finding
+    work     *big.Int
+}
 
-        tenYearsAgo := currentTime - (10 * 365 * 24 * 60 * 60)
-        if block.Timestamp < tenYearsAgo {
-            errMsg := fmt.Sprintf("Block %d: Timestamp too old", i)
-            result.TimestampPast = append(result.TimestampPast, errMsg)
-            result.TotalErrors++
+func newScan(result *ErrorScanResult, selected []rules.Rule, ctx *rules.Context) *scan {
+    s := &scan{result: result, ctx: ctx, enabled: make(map[string]rules.Rule), work: new(big.Int), verifiedWork: new(big.Int)}
+    for _, rule := range selected {
+        result.Rules = append(result.Rules, rule.ID())
+        s.enabled[rule.ID()] = rule
+        if r, ok := rule.(rules.BlockRule); ok {
+            s.blockRules = append(s.blockRules, r)
         }
-
-        if prevBlock != nil && block.Timestamp <= prevBlock.Timestamp {
-            errMsg := fmt.Sprintf("Block %d: Timestamp not increasing", i)
-            result.TimestampNotIncreasing = append(result.TimestampNotIncreasing, errMsg)
-            result.TotalErrors++
+        if r, ok := rule.(rules.ChainRule); ok {
+            s.chainRules = append(s.chainRules, r)
         }
-
-        if block.Data == "" || len(strings.TrimSpace(block.Data)) == 0 {
-            errMsg := fmt.Sprintf("Block %d: Empty block", i)
-            result.EmptyBlocks = append(result.EmptyBlocks, errMsg)
-            result.TotalErrors++
+        if r, ok := rule.(rules.Finisher); ok {
+            s.finishers = append(s.finishers, r)
         }
+    }
+    return s
+}
 
-        if i == 0 {
-            if block.PrevHash != "0" {
-                errMsg := fmt.Sprintf("Block 0: Invalid genesis prevHash")
-                result.PrevHashErrors = append(result.PrevHashErrors, errMsg)
-                result.TotalErrors++
-            }
-        } else if prevBlock != nil && block.PrevHash != prevBlock.Hash {
-            errMsg := fmt.Sprintf("Block %d: PrevHash linkage broken", i)
-            result.PrevHashErrors = append(result.PrevHashErrors, errMsg)
-            result.TotalErrors++
-        }
+// stamp labels findings with the rule that produced them and its severity
+// under the scan policy.
+func (s *scan) stamp(rule rules.Rule, findings []rules.Finding) []rules.Finding {
+    severity := s.ctx.Policy.SeverityOf(rule)
+    for i := range findings {
+        findings[i].RuleID, findings[i].Severity = rule.ID(), severity
+    }
+    return findings
+}
 
-        if block.Height != expectedHeight {
-            errMsg := fmt.Sprintf("Block %d: Height mismatch", i)
-            result.HeightErrors = append(result.HeightErrors, errMsg)
-            result.TotalErrors++
-        }
+func (s *scan) report(rule rules.Rule, findings []rules.Finding) {
+    s.result.Findings = append(s.result.Findings, s.stamp(rule, findings)...)
+}
 
-        if block.Height < expectedHeight {
-            errMsg := fmt.Sprintf("Block %d: Out of order", i)
-            result.OutOfOrderBlocks = append(result.OutOfOrderBlocks, errMsg)
-            result.TotalErrors++
+// check runs the block rules on an item, which only depends on the item.
+func (s *scan) check(item *scanItem) {
+    if item.block == nil && item.err == nil {
+        item.block, item.err = s.decode(item.raw)
+    }
+    if item.err != nil {
+        return
+    }
+    entry := rules.Entry{Height: item.height, Block: item.block}
+    for _, rule := range s.blockRules {
+        item.findings = append(item.findings, s.stamp(rule, rule.CheckBlock(s.ctx, entry))...)
+    }
+    item.work = blocks.ProvenWork(item.block, s.ctx.HashVersion, s.ctx.HashAlgorithm)
+}
+
+// consume takes a checked item in height order: it reports gaps before it
+// and decode failures, then runs the chain rules. verified follows the last
+// block before the first gap or undecodable value.
+func (s *scan) consume(item *scanItem) {
+    if s.next < item.height {
+        s.markMissing(s.next, item.height-1)
+    }
+    s.next = item.height + 1
+
+    if item.err != nil {
+        err := item.err
+        codecName := "unknown"
+        var decodeErr *codec.DecodeError
+        if errors.As(err, &decodeErr) {
+            codecName, err = decodeErr.Codec, decodeErr.Err
+        }
+        if rule := s.enabled[rules.DecodeError]; rule != nil {
+            s.report(rule, []rules.Finding{{Height: item.height, Message: fmt.Sprintf("Decode failed (%s) - %v", codecName, err)}})
         }
+        s.broken = true
+        return
+    }
 
-        prevBlock = &block
-        expectedHeight++
+    s.result.BlocksScanned++
+    s.result.Findings = append(s.result.Findings, item.findings...)
+    entry := rules.Entry{Height: item.height, Block: item.block}
+    for _, rule := range s.chainRules {
+        s.report(rule, rule.CheckPair(s.ctx, s.prev, entry))
     }
+    s.work.Add(s.work, item.work)
+    s.prev = &entry
+    if !s.broken {
+        s.verified = &entry
+        s.verifiedWork.Set(s.work)
+    }
+}
 
-    if result.BlocksScanned > 0 {
-        result.HealthScore = ((result.BlocksScanned - result.TotalErrors) * 100) / result.BlocksScanned
-        if result.HealthScore < 0 {
-            result.HealthScore = 0
+// markMissing reports the gap from..to once, however long it is.
+func (s *scan) markMissing(from, to int) {
+    s.broken = true
+    s.ctx.Missing = append(s.ctx.Missing, rules.Gap{From: from, To: to})
+    if rule := s.enabled[rules.MissingBlock]; rule != nil {
+        finding := rules.Finding{Height: from, Message: "Missing"}
+        if to > from {
+            finding.Span, finding.Message = to-from+1, fmt.Sprintf("Missing heights %d..%d (%d blocks)", from, to, to-from+1)
         }
+        s.report(rule, []rules.Finding{finding})
     }
+}
 
-    if result.TotalErrors == 0 {
-        result.Status = "HEALTHY"
-    } else {
-        result.Status = "ERRORS_FOUND"
+// finish reports the gap up to height and the finishing rules, then fills
+// in the totals.
+func (s *scan) finish(height int) {
+    result := s.result
+    if s.next <= height {
+        s.markMissing(s.next, height)
+        s.next = height + 1
     }
+    for _, rule := range s.finishers {
+        s.report(rule, rule.Finish(s.ctx))
+    }
+    if s.carried != nil {
+        s.carry()
+    }
+    sort.SliceStable(result.Findings, func(i, j int) bool { return result.Findings[i].Height < result.Findings[j].Height })
+    s.tally()
+    result.CumulativeWork = s.work.String()
+}
 
-    return result
+// tally counts the reported findings, zero included for every rule run.
+func (s *scan) tally() {
+    result := s.result
+    result.Counts = rules.Count(result.Findings)
+    for _, id := range result.Rules {
+        if _, ok := result.Counts[id]; !ok {
+            result.Counts[id] = 0
+        }
+    }
+    result.TotalFindings = len(result.Findings)
+    result.TotalErrors = 0
+    for _, f := range result.Findings {
+        if s.ctx.Policy.Fails(f.Severity) {
+            result.TotalErrors++
+        }
+    }
 }
@@ -0,0 +1,681 @@
+package errors
+
+import (
+    "crypto/ed25519"
+    "encoding/hex"
+    "fmt"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+
+    "inspector/internal/blocks"
+    "inspector/internal/chainspec"
+    "inspector/internal/db"
+    "inspector/internal/rules"
+    "inspector/internal/validators"
+)
+
+func buildChain(n int) []*blocks.Block {
+    chain := make([]*blocks.Block, 0, n)
+    prevHash := "0"
+    start := time.Now().Unix() - int64(n*10)
+    for i := 0; i < n; i++ {
+        data := fmt.Sprintf("Transaction data for block %d", i)
+        timestamp := start + int64(i*10)
+        block := &blocks.Block{
+            Height:    i,
+            Hash:      blocks.ComputeHash(i, prevHash, data, timestamp),
+            PrevHash:  prevHash,
+            Data:      data,
+            Timestamp: timestamp,
+        }
+        chain = append(chain, block)
+        prevHash = block.Hash
+    }
+    return chain
+}
+
+func TestScanHealthyChain(t *testing.T) {
+    source, _ := db.NewMemorySource(buildChain(20))
+
+    result := ScanErrors(source, "memory")
+    if result.Status != "HEALTHY" {
+        t.Errorf("Expected HEALTHY, got %s (%d errors)", result.Status, result.TotalErrors)
+    }
+    if result.BlocksScanned != 20 {
+        t.Errorf("Expected 20 blocks scanned, got %d", result.BlocksScanned)
+    }
+}
+
+func TestScanDetectsTampering(t *testing.T) {
+    chain := buildChain(10)
+    chain[4].Data = "tampered"
+    source, _ := db.NewMemorySource(chain)
+    source.PutRaw(7, []byte("{corrupt"))
+
+    result := ScanErrors(source, "memory")
+    badHash := result.FindingsFor("bad_hash")
+    if len(badHash) != 1 {
+        t.Fatalf("Expected 1 bad hash, got %v", badHash)
+    }
+    if f := badHash[0]; f.Height != 4 || f.Severity != rules.SeverityCritical || f.BlockHash != chain[4].Hash ||
+        f.Actual != chain[4].Hash || f.Expected == "" || f.Expected == f.Actual {
+        t.Errorf("Expected bad hash evidence for block 4, got %+v", f)
+    }
+    if len(result.FindingsFor("decode_error")) != 1 || !strings.Contains(result.FindingsFor("decode_error")[0].Message, "(json)") {
+        t.Errorf("Expected 1 json decode failure, got %v", result.FindingsFor("decode_error"))
+    }
+    if result.Counts["bad_hash"] != 1 || result.Counts["empty_block"] != 0 || result.TotalErrors != len(result.Findings) {
+        t.Errorf("Expected counts derived from findings, got %v", result.Counts)
+    }
+}
+
+func TestScanDetectsBadMerkleRoot(t *testing.T) {
+    chain := buildChain(5)
+    for _, block := range chain {
+        tx := blocks.Transaction{Sender: "alice", Receiver: "bob", Amount: int64(block.Height), Nonce: uint64(block.Height)}
+        tx.ID = blocks.ComputeTxID(tx)
+        block.Transactions = []blocks.Transaction{tx}
+        block.MerkleRoot = blocks.ComputeMerkleRoot(block.Transactions)
+    }
+    chain[2].Transactions[0].Amount = 1000
+    chain[3].MerkleRoot = "00"
+    source, _ := db.NewMemorySource(chain)
+
+    result := ScanErrors(source, "memory")
+    if len(result.FindingsFor("bad_merkle_root")) != 2 {
+        t.Errorf("Expected 2 bad merkle roots, got %v", result.FindingsFor("bad_merkle_root"))
+    }
+}
+
+func TestScanUsesDeclaredHashVersion(t *testing.T) {
+    chain := buildChain(6)
+    prevHash := "0"
+    for _, block := range chain {
+        block.PrevHash = prevHash
+        block.Hash, _ = blocks.ComputeBlockHash(block, blocks.HashV1)
+        prevHash = block.Hash
+    }
+    source, _ := db.NewMemorySource(chain)
+
+    if result := ScanErrors(source, "memory"); len(result.FindingsFor("bad_hash")) != 6 {
+        t.Errorf("Expected v1 blocks to fail legacy verification, got %v", result.FindingsFor("bad_hash"))
+    }
+    if result := ScanErrorsWithOptions(source, "memory", ScanOptions{HashVersion: blocks.HashV1}); result.Status != "HEALTHY" {
+        t.Errorf("Expected HEALTHY with chain default v1, got %s", result.Status)
+    }
+
+    for _, block := range chain {
+        block.DeclareHashVersion(blocks.HashV1)
+    }
+    source, _ = db.NewMemorySource(chain)
+    if result := ScanErrors(source, "memory"); result.Status != "HEALTHY" {
+        t.Errorf("Expected blocks declaring v1 to verify, got %v", result.FindingsFor("bad_hash"))
+    }
+}
+
+func rehash(chain []*blocks.Block, algorithm string) {
+    prevHash := "0"
+    for _, block := range chain {
+        block.PrevHash = prevHash
+        block.Hash, _ = blocks.ComputeBlockHashWith(block, blocks.HashV0, algorithm)
+        prevHash = block.Hash
+    }
+}
+
+func TestScanWithHashAlgorithm(t *testing.T) {
+    chain := buildChain(5)
+    rehash(chain, "blake2b-256")
+    source, _ := db.NewMemorySource(chain)
+
+    if result := ScanErrors(source, "memory"); len(result.FindingsFor("bad_hash")) != 5 {
+        t.Errorf("Expected blake2b blocks to fail sha256 verification, got %v", result.FindingsFor("bad_hash"))
+    }
+    result := ScanErrorsWithOptions(source, "memory", ScanOptions{HashAlgorithm: "blake2b-256"})
+    if result.Status != "HEALTHY" {
+        t.Errorf("Expected HEALTHY with blake2b-256, got %v", result.FindingsFor("bad_hash"))
+    }
+    if result := ScanErrorsWithOptions(source, "memory", ScanOptions{HashAlgorithm: "nope"}); !strings.HasPrefix(result.Status, "ERROR") {
+        t.Errorf("Expected unknown algorithm to stop the scan, got %s", result.Status)
+    }
+}
+
+// oddDataRule stands in for a chain-specific rule registered outside the
+// scanner.
+type oddDataRule struct{ rules.Meta }
+
+func (r oddDataRule) CheckBlock(ctx *rules.Context, e rules.Entry) []rules.Finding {
+    if strings.Contains(e.Block.Data, "odd") {
+        return []rules.Finding{{Height: e.Height, Message: "Odd data"}}
+    }
+    return nil
+}
+
+func TestScanRunsRegisteredRules(t *testing.T) {
+    rules.Register(func() rules.Rule {
+        return oddDataRule{rules.Meta{RuleID: "test_odd_data", Desc: "Data mentions odd", Level: rules.SeverityWarning}}
+    })
+    t.Cleanup(func() { rules.Unregister("test_odd_data") })
+    chain := buildChain(5)
+    chain[3].Data = "odd"
+    source, _ := db.NewMemorySource(chain)
+
+    result := ScanErrors(source, "memory")
+    if len(result.FindingsFor("test_odd_data")) != 1 || len(result.FindingsFor("bad_hash")) != 1 {
+        t.Errorf("Expected the registered rule and bad_hash to fire, got %v", result.Findings)
+    }
+
+    result = ScanErrorsWithOptions(source, "memory", ScanOptions{EnabledRules: []string{"test_odd_data", "bad_hash"}, DisabledRules: []string{"bad_hash"}})
+    if len(result.Rules) != 1 || result.TotalFindings != 1 {
+        t.Errorf("Expected only test_odd_data to run, got %v", result.Findings)
+    }
+    if result := ScanErrorsWithOptions(source, "memory", ScanOptions{DisabledRules: []string{"nope"}}); !strings.HasPrefix(result.Status, "ERROR") {
+        t.Errorf("Expected unknown rule id to stop the scan, got %s", result.Status)
+    }
+}
+
+func TestCompareReportsValidNode(t *testing.T) {
+    chain1 := buildChain(4)
+    rehash(chain1, "sha256d")
+    chain2 := make([]*blocks.Block, len(chain1))
+    for i, block := range chain1 {
+        clone := *block
+        chain2[i] = &clone
+    }
+    chain2[3].Data = "forged"
+    chain2[3].Hash = "forged-hash"
+    source1, _ := db.NewMemorySource(chain1)
+    source2, _ := db.NewMemorySource(chain2)
+
+    opts := ScanOptions{HashAlgorithm: "sha256d"}
+    result := CompareNodesWithOptions(source1, source2, "a", "b", opts, opts)
+    if result.NodeCount("bad_hash", "node1") != 0 || result.NodeCount("bad_hash", "node2") != 1 {
+        t.Errorf("Expected only node2 block 3 invalid, got %v", result.Findings)
+    }
+    var mismatch *rules.Finding
+    for i := range result.Findings {
+        if result.Findings[i].RuleID == HashMismatch {
+            mismatch = &result.Findings[i]
+        }
+    }
+    if mismatch == nil || !strings.Contains(mismatch.Message, "node1 valid, node2 invalid") || mismatch.Actual != "forged-hash" {
+        t.Errorf("Expected mismatch to name the valid node, got %v", result.Findings)
+    }
+}
+
+func TestScanChecksSignatures(t *testing.T) {
+    pub, priv, _ := ed25519.GenerateKey(nil)
+    _, stranger, _ := ed25519.GenerateKey(nil)
+    set, _ := validators.NewSet([]validators.Validator{{Name: "v1", PublicKey: hex.EncodeToString(pub)}})
+
+    chain := buildChain(6)
+    for _, block := range chain {
+        blocks.SignBlock(block, priv)
+    }
+    chain[2].Signature, chain[2].Proposer = "", ""
+    chain[3].Signature = chain[4].Signature
+    blocks.SignBlock(chain[5], stranger)
+    source, _ := db.NewMemorySource(chain)
+
+    result := ScanErrorsWithOptions(source, "memory", ScanOptions{Validators: set})
+    if len(result.FindingsFor("missing_signature")) != 1 {
+        t.Errorf("Expected 1 missing signature, got %v", result.FindingsFor("missing_signature"))
+    }
+    if len(result.FindingsFor("invalid_signature")) != 1 {
+        t.Errorf("Expected 1 invalid signature, got %v", result.FindingsFor("invalid_signature"))
+    }
+    if len(result.FindingsFor("unknown_proposer")) != 1 {
+        t.Errorf("Expected 1 unknown proposer, got %v", result.FindingsFor("unknown_proposer"))
+    }
+
+    result = ScanErrors(source, "memory")
+    if len(result.FindingsFor("missing_signature")) != 0 || len(result.FindingsFor("invalid_signature")) != 1 {
+        t.Errorf("Without a validator set only bad signatures count, got %v / %v", result.FindingsFor("missing_signature"), result.FindingsFor("invalid_signature"))
+    }
+}
+
+func TestScanChecksProofOfWork(t *testing.T) {
+    pow := &blocks.PowParams{InitialDifficulty: 4, RetargetWindow: 4, TargetSpacing: 10, MaxAdjust: 1}
+    chain := buildChain(8)
+    prevHash := "0"
+    difficulty := 0
+    for i, block := range chain {
+        var start, end int64
+        if pow.IsRetarget(i) {
+            start, end = chain[i-4].Timestamp, chain[i-1].Timestamp
+        }
+        difficulty = pow.ExpectedDifficulty(i, difficulty, start, end)
+        block.PrevHash = prevHash
+        block.Difficulty = difficulty
+        block.DeclareHashVersion(blocks.HashV2)
+        if err := blocks.Mine(block, blocks.HashV2, "", 1<<20); err != nil {
+            t.Fatal(err)
+        }
+        prevHash = block.Hash
+    }
+    source, _ := db.NewMemorySource(chain)
+
+    result := ScanErrorsWithOptions(source, "memory", ScanOptions{Pow: pow})
+    if result.Status != "HEALTHY" {
+        t.Fatalf("Expected mined chain to be HEALTHY, got %v / %v", result.FindingsFor("insufficient_work"), result.FindingsFor("difficulty"))
+    }
+    if result.CumulativeWork != "128" {
+        t.Errorf("Expected cumulative work 128, got %s", result.CumulativeWork)
+    }
+
+    chain[6].Difficulty = 5
+    chain[6].Hash, _ = blocks.ComputeBlockHash(chain[6], blocks.HashV2)
+    source, _ = db.NewMemorySource(chain)
+    result = ScanErrorsWithOptions(source, "memory", ScanOptions{Pow: pow})
+    if len(result.FindingsFor("difficulty")) != 2 {
+        t.Errorf("Expected difficulty errors at blocks 6 and 7, got %v", result.FindingsFor("difficulty"))
+    }
+    if blocks.LeadingZeroBits(chain[6].Hash) < 5 && len(result.FindingsFor("insufficient_work")) != 1 {
+        t.Errorf("Expected block 6 to lack work, got %v", result.FindingsFor("insufficient_work"))
+    }
+}
+
+func TestScanChecksChainSpec(t *testing.T) {
+    chain := buildChain(6)
+    spec := &chainspec.Spec{
+        ChainID:     "test",
+        GenesisHash: chain[0].Hash,
+        Checkpoints: []chainspec.Checkpoint{{Height: 2, Hash: chain[2].Hash}, {Height: 4, Hash: "00ff"}},
+    }
+    source, _ := db.NewMemorySource(chain)
+
+    result := ScanErrorsWithOptions(source, "memory", ScanOptions{Spec: spec})
+    if len(result.FindingsFor("chain_spec")) != 1 || !strings.Contains(result.FindingsFor("chain_spec")[0].String(), "Block 4") {
+        t.Errorf("Expected checkpoint 4 to fail, got %v", result.FindingsFor("chain_spec"))
+    }
+    if result.ChainID != "test" {
+        t.Errorf("Expected chain id in result, got %q", result.ChainID)
+    }
+
+    spec.GenesisPrevHash = "genesis-parent"
+    result = ScanErrorsWithOptions(source, "memory", ScanOptions{Spec: spec})
+    if len(result.FindingsFor("prev_hash")) != 1 {
+        t.Errorf("Expected genesis prev hash from the spec to apply, got %v", result.FindingsFor("prev_hash"))
+    }
+}
+
+func TestCompareFlagsDifferentChains(t *testing.T) {
+    source1, _ := db.NewMemorySource(buildChain(3))
+    other := buildChain(3)
+    other[0].Data = "another testnet"
+    rehash(other, "")
+    source2, _ := db.NewMemorySource(other)
+
+    result := CompareNodes(source1, source2, "a", "b")
+    if !result.DifferentChains {
+        t.Error("Expected different genesis blocks to be flagged")
+    }
+}
+
+func TestCompareDivergentSources(t *testing.T) {
+    chain1 := buildChain(10)
+    chain2 := make([]*blocks.Block, len(chain1))
+    for i, block := range chain1 {
+        clone := *block
+        chain2[i] = &clone
+    }
+    chain2[6].Hash = "forked"
+    source1, _ := db.NewMemorySource(chain1)
+    source2, _ := db.NewMemorySource(chain2[:8])
+
+    result := CompareNodes(source1, source2, "a", "b")
+    if result.DivergencePoint != 6 {
+        t.Errorf("Expected divergence at 6, got %d", result.DivergencePoint)
+    }
+    if result.NodeCount(MissingOnNode, "node2") != 2 {
+        t.Errorf("Expected 2 blocks missing on node2, got %v", result.Findings)
+    }
+}
+
+// damagedChain is a signed chain with one of most kinds of damage.
+func damagedChain(n int) *db.MemorySource {
+    _, priv, _ := ed25519.GenerateKey(nil)
+    chain := buildChain(n)
+    for _, block := range chain {
+        blocks.SignBlock(block, priv)
+    }
+    chain[3].Data = "tampered"
+    chain[5].Hash = chain[4].Hash
+    chain[9].Timestamp = chain[8].Timestamp
+    chain[9].Signature = chain[8].Signature
+    source, _ := db.NewMemorySource(append(chain[:11:11], chain[12:]...))
+    source.PutRaw(7, []byte("{corrupt"))
+    return source
+}
+
+func TestParallelScanMatchesSequential(t *testing.T) {
+    source := damagedChain(300)
+
+    sequential := ScanErrors(source, "memory")
+    if sequential.TotalErrors == 0 {
+        t.Fatal("Expected the damaged chain to have findings")
+    }
+    for _, workers := range []int{2, 8} {
+        parallel := ScanErrorsWithOptions(source, "memory", ScanOptions{Workers: workers})
+        if !reflect.DeepEqual(parallel.Findings, sequential.Findings) || parallel.BlocksScanned != sequential.BlocksScanned ||
+            parallel.CumulativeWork != sequential.CumulativeWork {
+            t.Errorf("%d workers: findings differ from the sequential scan\n%v\n%v", workers, parallel.Findings, sequential.Findings)
+        }
+    }
+}
+
+func BenchmarkScan(b *testing.B) {
+    source := damagedChain(5000)
+    for _, workers := range []int{1, 4, 8} {
+        b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+            for i := 0; i < b.N; i++ {
+                ScanErrorsWithOptions(source, "memory", ScanOptions{Workers: workers})
+            }
+        })
+    }
+}
+
+func TestIncrementalScanResumes(t *testing.T) {
+    chain := buildChain(15)
+    source, _ := db.NewMemorySource(chain[:10])
+    opts := ScanOptions{Checkpoint: filepath.Join(t.TempDir(), "scan.json"), Incremental: true}
+
+    result := ScanErrorsWithOptions(source, "memory", opts)
+    if result.Checkpoint.Resumed || result.BlocksScanned != 10 || result.Checkpoint.Height != 9 {
+        t.Fatalf("Expected a full first scan verified to 9, got %+v (%d scanned)", result.Checkpoint, result.BlocksScanned)
+    }
+
+    for _, block := range chain[10:] {
+        source.SaveBlock(block)
+    }
+    result = ScanErrorsWithOptions(source, "memory", opts)
+    if !result.Checkpoint.Resumed || result.Coverage.From != 10 || result.BlocksScanned != 5 || result.Status != "HEALTHY (partial coverage)" {
+        t.Errorf("Expected to resume at 10 and link to the checkpoint, got %+v, %d scanned, %v", result.Checkpoint, result.BlocksScanned, result.Findings)
+    }
+
+    forked := *chain[14]
+    forked.Data = "reorg"
+    forked.Hash = blocks.ComputeHash(forked.Height, forked.PrevHash, forked.Data, forked.Timestamp)
+    source.SaveBlock(&forked)
+    result = ScanErrorsWithOptions(source, "memory", opts)
+    changed := result.FindingsFor("checkpoint_changed")
+    if result.Checkpoint.Resumed || result.BlocksScanned != 15 || len(changed) != 1 || changed[0].Expected != chain[14].Hash {
+        t.Errorf("Expected the changed checkpoint to be reported and a full scan, got %+v, %v", result.Checkpoint, result.Findings)
+    }
+
+    opts.DisabledRules = []string{"empty_block"}
+    if result := ScanErrorsWithOptions(source, "memory", opts); result.Checkpoint.Resumed {
+        t.Error("Expected a different rule set to force a full scan")
+    }
+}
+
+func TestIncrementalScanCarriesFindings(t *testing.T) {
+    chain := buildChain(15)
+    chain[3].Data = "tampered"
+    source, _ := db.NewMemorySource(chain[:10])
+    opts := ScanOptions{Checkpoint: filepath.Join(t.TempDir(), "scan.json"), Incremental: true}
+    if result := ScanErrorsWithOptions(source, "memory", opts); len(result.FindingsFor("bad_hash")) != 1 || result.Checkpoint.Height != 9 {
+        t.Fatalf("Expected block 3 reported and the chain verified to 9, got %+v, %v", result.Checkpoint, result.Findings)
+    }
+
+    for _, block := range chain[10:14] {
+        source.SaveBlock(block)
+    }
+    duplicate := *chain[3]
+    duplicate.Height = 14
+    source.SaveBlock(&duplicate)
+    result := ScanErrorsWithOptions(source, "memory", opts)
+    badHash := result.FindingsFor("bad_hash")
+    if !result.Checkpoint.Resumed || len(badHash) != 2 || badHash[0].Height != 3 || result.Checkpoint.Carried != 1 {
+        t.Errorf("Expected block 3 carried from the checkpoint, got %+v, %v", result.Checkpoint, result.Findings)
+    }
+    if c := result.Coverage; !c.Partial || c.Mode != "incremental" || result.Status != "ERRORS_FOUND (partial coverage)" {
+        t.Errorf("Expected partial incremental coverage, got %+v, %s", c, result.Status)
+    }
+    // Whole-chain rules only see the blocks since the checkpoint.
+    if len(result.FindingsFor("duplicate_hash")) != 0 {
+        t.Errorf("Expected the duplicate of block 3 to go unseen, got %v", result.FindingsFor("duplicate_hash"))
+    }
+
+    again := ScanErrorsWithOptions(source, "memory", opts)
+    if again.BlocksScanned != 0 || again.Checkpoint.Carried != len(result.Findings) || !reflect.DeepEqual(again.Findings, result.Findings) {
+        t.Errorf("Expected a scan with nothing new to report the same findings, got %+v, %v", again.Checkpoint, again.Findings)
+    }
+    if full := ScanErrors(source, "memory"); len(full.FindingsFor("duplicate_hash")) != 1 {
+        t.Errorf("Expected a full scan to see the duplicate, got %v", full.Findings)
+    }
+}
+
+func TestIncrementalScanSeedsWindows(t *testing.T) {
+    chain := buildChain(30)
+    chain[20].Timestamp = chain[12].Timestamp
+    rehash(chain, "")
+    full, _ := db.NewMemorySource(chain)
+    expected := ScanErrors(full, "memory").FindingsFor("median_time_past")
+    if len(expected) == 0 || expected[0].Height != 20 {
+        t.Fatalf("Expected block 20 before the median time past, got %v", expected)
+    }
+
+    source, _ := db.NewMemorySource(chain[:20])
+    opts := ScanOptions{Checkpoint: filepath.Join(t.TempDir(), "scan.json"), Incremental: true}
+    ScanErrorsWithOptions(source, "memory", opts)
+    for _, block := range chain[20:] {
+        source.SaveBlock(block)
+    }
+    result := ScanErrorsWithOptions(source, "memory", opts)
+    if got := result.FindingsFor("median_time_past"); !result.Checkpoint.Resumed || !reflect.DeepEqual(got, expected) {
+        t.Errorf("Expected the resumed scan to judge block 20 as a full scan does, got %v, want %v", got, expected)
+    }
+}
+
+func TestPartialScansSeedWindows(t *testing.T) {
+    chain := buildChain(30)
+    chain[20].Timestamp = chain[12].Timestamp
+    rehash(chain, "")
+    source, _ := db.NewMemorySource(chain)
+
+    ranged := ScanErrorsWithOptions(source, "memory", ScanOptions{Range: &ScanRange{From: 20, To: -1}})
+    if got := ranged.FindingsFor("median_time_past"); len(got) == 0 || got[0].Height != 20 {
+        t.Errorf("Expected the range to judge block 20 by the window below it, got %v", got)
+    }
+
+    sample := ScanErrorsWithOptions(source, "memory", ScanOptions{Range: &ScanRange{To: -1, Sample: 15, SampleMode: "stride"}})
+    if got := sample.FindingsFor("median_time_past"); len(got) != 1 || got[0].Height != 20 {
+        t.Errorf("Expected sampled block 20 judged by its own window, got %v", got)
+    }
+}
+
+func TestRangeScanLinksToPrecedingBlock(t *testing.T) {
+    chain := buildChain(20)
+    source, _ := db.NewMemorySource(chain)
+
+    result := ScanErrorsWithOptions(source, "memory", ScanOptions{Range: &ScanRange{From: 10, To: 14}})
+    if result.BlocksScanned != 5 || len(result.Findings) != 0 || result.Status != "HEALTHY (partial coverage)" {
+        t.Errorf("Expected a clean partial scan of 5 blocks, got %d scanned, %s, %v", result.BlocksScanned, result.Status, result.Findings)
+    }
+    if c := result.Coverage; c.Mode != "range" || c.Heights != 5 || c.Percent != 25 {
+        t.Errorf("Expected range coverage of 5 heights (25%%), got %+v", c)
+    }
+
+    broken := *chain[10]
+    broken.PrevHash = chain[8].Hash
+    broken.Hash = blocks.ComputeHash(broken.Height, broken.PrevHash, broken.Data, broken.Timestamp)
+    source.SaveBlock(&broken)
+    result = ScanErrorsWithOptions(source, "memory", ScanOptions{Range: &ScanRange{From: 10, To: 10}})
+    if got := result.FindingsFor("prev_hash"); len(got) != 1 || got[0].Height != 10 {
+        t.Errorf("Expected the link into the range to be checked, got %v", result.Findings)
+    }
+}
+
+func TestSampleScan(t *testing.T) {
+    source, _ := db.NewMemorySource(buildChain(20))
+
+    stride := ScanErrorsWithOptions(source, "memory", ScanOptions{Range: &ScanRange{To: -1, Sample: 4, SampleMode: "stride"}})
+    if stride.BlocksScanned != 4 || len(stride.Findings) != 0 || stride.Coverage.Heights != 4 {
+        t.Errorf("Expected 4 clean sampled blocks, got %d scanned, %+v, %v", stride.BlocksScanned, stride.Coverage, stride.Findings)
+    }
+    if heights, _ := sampleHeights(ScanRange{From: 0, To: 19, Sample: 4, SampleMode: "stride"}); !reflect.DeepEqual(heights, []int{0, 5, 10, 15}) {
+        t.Errorf("Expected evenly spaced heights, got %v", heights)
+    }
+
+    r := ScanRange{From: 2, To: 17, Sample: 6, SampleMode: "random", Seed: 42}
+    first, _ := sampleHeights(r)
+    second, _ := sampleHeights(r)
+    if len(first) != 6 || !reflect.DeepEqual(first, second) {
+        t.Errorf("Expected the same 6 heights for the same seed, got %v and %v", first, second)
+    }
+    for i, height := range first {
+        if height < 2 || height > 17 || (i > 0 && height <= first[i-1]) {
+            t.Errorf("Expected distinct ascending heights within 2..17, got %v", first)
+        }
+    }
+    // A small sample of a huge range must not walk the range.
+    if wide, _ := sampleHeights(ScanRange{From: 0, To: 1 << 40, Sample: 3, SampleMode: "random", Seed: 7}); len(wide) != 3 || wide[0] >= wide[1] || wide[1] >= wide[2] {
+        t.Errorf("Expected 3 ascending heights, got %v", wide)
+    }
+
+    random := ScanErrorsWithOptions(source, "memory", ScanOptions{Range: &ScanRange{To: -1, Sample: 5, SampleMode: "random"}})
+    if random.Coverage.Seed == 0 || random.BlocksScanned != 5 {
+        t.Errorf("Expected a recorded seed and 5 blocks, got %+v", random.Coverage)
+    }
+}
+
+func TestScanPolicy(t *testing.T) {
+    chain := buildChain(5)
+    chain[0].Timestamp = 1000
+    chain[2].Data = ""
+    chain[4].Timestamp = time.Now().Unix() + 200
+    rehash(chain, "sha256")
+    source, _ := db.NewMemorySource(chain)
+
+    result := ScanErrors(source, "memory")
+    if result.Counts["timestamp_past"] != 1 || result.Counts["empty_block"] != 1 || result.Counts["timestamp_future"] != 0 {
+        t.Errorf("Expected the default policy to flag the old and empty blocks only, got %v", result.Findings)
+    }
+    if result.Policy.ClockSkew != 300 || result.Policy.MinTimestamp == 0 {
+        t.Errorf("Expected the resolved default policy in the result, got %+v", result.Policy)
+    }
+
+    policy := rules.DefaultPolicy()
+    policy.ClockSkew, policy.MinTimestamp, policy.AllowEmptyBlocks = 60, 1000, true
+    policy.Severity = map[string]rules.Severity{"timestamp_future": rules.SeverityCritical}
+    result = ScanErrorsWithOptions(source, "memory", ScanOptions{Policy: policy})
+    future := result.FindingsFor("timestamp_future")
+    if len(future) != 1 || future[0].Severity != rules.SeverityCritical || result.TotalErrors != 1 {
+        t.Errorf("Expected only a critical future timestamp under the policy, got %v", result.Findings)
+    }
+
+    policy.Severity["nope"] = rules.SeverityInfo
+    if result := ScanErrorsWithOptions(source, "memory", ScanOptions{Policy: policy}); !strings.HasPrefix(result.Status, "ERROR") {
+        t.Errorf("Expected a severity for an unknown rule to stop the scan, got %s", result.Status)
+    }
+}
+
+func TestWarningsLeaveChainHealthy(t *testing.T) {
+    chain := buildChain(5)
+    chain[2].Data = ""
+    rehash(chain, "sha256")
+    source, _ := db.NewMemorySource(chain)
+
+    result := ScanErrors(source, "memory")
+    if result.Counts["empty_block"] != 1 || result.TotalFindings != 1 || result.TotalErrors != 0 || result.Status != "HEALTHY" {
+        t.Errorf("Expected a reported warning on a healthy chain, got %d of %d failing, %s", result.TotalErrors, result.TotalFindings, result.Status)
+    }
+
+    policy := rules.DefaultPolicy()
+    policy.FailOn = rules.SeverityWarning
+    if result := ScanErrorsWithOptions(source, "memory", ScanOptions{Policy: policy}); result.TotalErrors != 1 || result.Status != "ERRORS_FOUND" {
+        t.Errorf("Expected fail_on warning to fail the scan, got %d, %s", result.TotalErrors, result.Status)
+    }
+    policy.FailOn = "minor"
+    if result := ScanErrorsWithOptions(source, "memory", ScanOptions{Policy: policy}); !strings.HasPrefix(result.Status, "ERROR:") {
+        t.Errorf("Expected an unknown fail_on to stop the scan, got %s", result.Status)
+    }
+}
+
+func TestBaselineSuppressesAcceptedFindings(t *testing.T) {
+    chain := buildChain(10)
+    chain[3].Data = ""
+    chain[6].Data = ""
+    rehash(chain, "sha256")
+    source, _ := db.NewMemorySource(chain)
+
+    baseline := NewBaseline(ScanErrors(source, "memory"))
+    if len(baseline.Entries) != 2 {
+        t.Fatalf("Expected both empty blocks in the baseline, got %v", baseline.Entries)
+    }
+    path := filepath.Join(t.TempDir(), "baseline.json")
+    if err := SaveBaseline(path, baseline); err != nil {
+        t.Fatal(err)
+    }
+    baseline, _ = LoadBaseline(path)
+
+    result := ScanErrorsWithOptions(source, "memory", ScanOptions{Baseline: baseline, BaselinePath: path})
+    if result.Status != "HEALTHY" || len(result.Suppressed) != 2 || len(result.Baseline.Stale) != 0 {
+        t.Errorf("Expected accepted findings to be suppressed, got %s, %v", result.Status, result.Findings)
+    }
+    // A stride sample of 0 and 5 never looks at blocks 3 and 6.
+    sample := &ScanRange{To: -1, Sample: 2, SampleMode: "stride"}
+    if result := ScanErrorsWithOptions(source, "memory", ScanOptions{Range: sample, Baseline: baseline, BaselinePath: path}); result.Baseline.Resolved != 0 {
+        t.Errorf("Expected no entries resolved at heights the sample skipped, got %+v", result.Baseline)
+    }
+
+    changed := *chain[6]
+    changed.Timestamp++
+    changed.Hash = blocks.ComputeHash(changed.Height, changed.PrevHash, changed.Data, changed.Timestamp)
+    source.SaveBlock(&changed)
+    result = ScanErrorsWithOptions(source, "memory", ScanOptions{Baseline: baseline, BaselinePath: path})
+    if len(result.Baseline.Stale) != 1 || len(result.Suppressed) != 1 || result.Counts["empty_block"] != 1 || result.Status != "ERRORS_FOUND" {
+        t.Errorf("Expected the changed block to be stale and reported again, got %+v, %v", result.Baseline, result.Findings)
+    }
+}
+
+func TestScanReportsEachGapOnce(t *testing.T) {
+    chain := buildChain(5)
+    stray := *chain[4]
+    stray.Height = 1 << 40
+    source, _ := db.NewMemorySource(append(chain[:3:3], &stray))
+
+    result := ScanErrors(source, "memory")
+    missing, segments := result.FindingsFor("missing_block"), result.FindingsFor("detached_segment")
+    if len(missing) != 1 || missing[0].Height != 3 || missing[0].Span != 1<<40-3 {
+        t.Errorf("Expected one finding for the gap 3..2^40-1, got %v", missing)
+    }
+    if len(segments) != 1 || !strings.Contains(segments[0].Message, fmt.Sprintf("3..%d", 1<<40-1)) {
+        t.Errorf("Expected the stray block to start a segment after the gap, got %v", segments)
+    }
+    if result.Health.Affected != 1<<40-2 || result.HealthScore != 50 {
+        t.Errorf("Expected every missing height charged, got %+v", result.Health)
+    }
+    if result.TipHeight != 1<<40 || result.ContiguousHeight != 2 {
+        t.Errorf("Expected tip 2^40 contiguous to 2, got %d and %d", result.TipHeight, result.ContiguousHeight)
+    }
+}
+
+func TestScanFindsDetachedAndOrphanedBlocks(t *testing.T) {
+    chain := buildChain(12)
+    chain[10].PrevHash = "feed"
+    chain[10].Hash = blocks.ComputeHash(10, chain[10].PrevHash, chain[10].Data, chain[10].Timestamp)
+    chain[11].PrevHash = chain[2].Hash
+    chain[11].Hash = blocks.ComputeHash(11, chain[11].PrevHash, chain[11].Data, chain[11].Timestamp)
+    source, _ := db.NewMemorySource(append(chain[:5:5], chain[7:]...))
+
+    result := ScanErrors(source, "memory")
+    segments, orphans, side := result.FindingsFor("detached_segment"), result.FindingsFor("orphan_block"), result.FindingsFor("side_chain_block")
+    if len(segments) != 1 || segments[0].Height != 7 || !strings.Contains(segments[0].Message, "5..6") {
+        t.Errorf("Expected one segment starting at 7 after 5..6, got %v", segments)
+    }
+    if len(orphans) != 2 || orphans[0].Height != 7 || orphans[1].Height != 10 || orphans[1].Actual != "feed" {
+        t.Errorf("Expected blocks 7 (parent in the gap) and 10 to be orphans, got %v", orphans)
+    }
+    if len(side) != 1 || side[0].Height != 11 || side[0].Actual != "2" {
+        t.Errorf("Expected block 11 on a side chain from 2, got %v", side)
+    }
+
+    partial := ScanErrorsWithOptions(source, "memory", ScanOptions{Range: &ScanRange{From: 8, To: -1}})
+    if len(partial.FindingsFor("orphan_block")) != 0 {
+        t.Errorf("Expected a partial scan not to guess at orphans, got %v", partial.Findings)
+    }
+}
@@ -0,0 +1,197 @@
+// Package health turns findings into a 0-100 score with a grade and a
+// breakdown of what cost the points.
+//
+// The score is the share of units (blocks for a scan, nodes for consensus)
+// in good order. Each unit loses the weight of its worst issue, between 0
+// (harmless) and 1 (the whole unit lost), so a block with three errors
+// costs no more than its worst one. Weights come from the issue's category
+// if the model names it, otherwise from its severity.
+package health
+
+import (
+    "fmt"
+    "math"
+    "sort"
+)
+
+// Model holds the weights and grades a score is computed with. Grades are
+// checked from the highest Min down; a score gets the first grade it
+// reaches.
+type Model struct {
+    SeverityWeights map[string]float64 `json:"severity_weights,omitempty"`
+    RuleWeights     map[string]float64 `json:"rule_weights,omitempty"`
+    Grades          []Grade            `json:"grades,omitempty"`
+}
+
+type Grade struct {
+    Name string  `json:"name"`
+    Min  float64 `json:"min"`
+}
+
+// DefaultModel weighs a critical issue as the whole unit, an error as
+// half, a warning as a tenth and info as nothing.
+func DefaultModel() Model {
+    return Model{
+        SeverityWeights: map[string]float64{"info": 0, "warning": 0.1, "error": 0.5, "critical": 1},
+        RuleWeights:     map[string]float64{},
+        Grades: []Grade{
+            {"EXCELLENT", 95},
+            {"GOOD", 85},
+            {"FAIR", 70},
+            {"POOR", 50},
+            {"CRITICAL", 0},
+        },
+    }
+}
+
+func (m Model) Validate() error {
+    for name, weights := range map[string]map[string]float64{"severity_weights": m.SeverityWeights, "rule_weights": m.RuleWeights} {
+        for key, weight := range weights {
+            if weight < 0 || weight > 1 {
+                return fmt.Errorf("%s: weight of %s must be between 0 and 1, got %g", name, key, weight)
+            }
+        }
+    }
+    if len(m.Grades) == 0 {
+        return fmt.Errorf("at least one grade is required")
+    }
+    for _, grade := range m.Grades {
+        if grade.Name == "" || grade.Min < 0 || grade.Min > 100 {
+            return fmt.Errorf("grade %q needs a name and a min between 0 and 100", grade.Name)
+        }
+    }
+    return nil
+}
+
+// Weight is what an issue of category and severity costs its unit.
+func (m Model) Weight(category, severity string) float64 {
+    if weight, ok := m.RuleWeights[category]; ok {
+        return weight
+    }
+    return m.SeverityWeights[severity]
+}
+
+// Grade names the score's grade, or "" if no grade covers it.
+func (m Model) Grade(score float64) string {
+    grades := append([]Grade(nil), m.Grades...)
+    sort.SliceStable(grades, func(i, j int) bool { return grades[i].Min > grades[j].Min })
+    for _, grade := range grades {
+        if score >= grade.Min {
+            return grade.Name
+        }
+    }
+    return ""
+}
+
+// Issue is one problem with one unit, e.g. a finding on a block, or with
+// Span units from Unit on, e.g. a gap of missing blocks.
+type Issue struct {
+    Unit     int
+    Span     int
+    Category string
+    Severity string
+}
+
+// Score is a computed score and how it came about. Breakdown lists every
+// category found, most costly first.
+type Score struct {
+    Score     float64        `json:"score"`
+    Grade     string         `json:"grade"`
+    Units     int            `json:"units"`
+    Affected  int            `json:"affected"`
+    Breakdown []Contribution `json:"breakdown"`
+}
+
+// Contribution is what one category cost. Issues counts all of them;
+// Charged counts the units it was the worst issue of, each costing Weight,
+// for Points off the score in total.
+type Contribution struct {
+    Category string  `json:"category"`
+    Severity string  `json:"severity"`
+    Weight   float64 `json:"weight"`
+    Issues   int     `json:"issues"`
+    Charged  int     `json:"charged"`
+    Points   float64 `json:"points"`
+}
+
+// Score rates units, of which those in issues have problems. Issues with a
+// Span must not overlap each other.
+func (m Model) Score(units int, issues []Issue) Score {
+    type span struct {
+        from, to int
+        c        *Contribution
+    }
+    byCategory := make(map[string]*Contribution)
+    worst := make(map[int]*Contribution)
+    var spans []span
+    var order []string
+    for _, issue := range issues {
+        c := byCategory[issue.Category]
+        if c == nil {
+            c = &Contribution{Category: issue.Category, Severity: issue.Severity, Weight: m.Weight(issue.Category, issue.Severity)}
+            byCategory[issue.Category] = c
+            order = append(order, issue.Category)
+        }
+        c.Issues++
+        if issue.Span > 1 {
+            spans = append(spans, span{issue.Unit, issue.Unit + issue.Span - 1, c})
+            continue
+        }
+        if w := worst[issue.Unit]; w == nil || c.Weight > w.Weight {
+            worst[issue.Unit] = c
+        }
+    }
+
+    // A span is charged for every unit it covers but those with a worse
+    // issue of their own.
+    affected := 0
+    for _, s := range spans {
+        covered := s.to - s.from + 1
+        for unit, w := range worst {
+            switch {
+            case unit < s.from || unit > s.to:
+            case w.Weight >= s.c.Weight:
+                covered--
+            default:
+                delete(worst, unit)
+            }
+        }
+        s.c.Charged += covered
+        affected += covered
+    }
+    for _, c := range worst {
+        c.Charged++
+    }
+    affected += len(worst)
+    if units < affected {
+        units = affected
+    }
+
+    score := Score{Score: 100, Units: units, Affected: affected, Breakdown: []Contribution{}}
+    for _, category := range order {
+        c := byCategory[category]
+        if units > 0 {
+            c.Points = round(float64(c.Charged) * c.Weight * 100 / float64(units))
+            score.Score -= float64(c.Charged) * c.Weight * 100 / float64(units)
+        }
+        score.Breakdown = append(score.Breakdown, *c)
+    }
+    sort.SliceStable(score.Breakdown, func(i, j int) bool { return score.Breakdown[i].Points > score.Breakdown[j].Points })
+    score.Score = round(math.Max(score.Score, 0))
+    score.Grade = m.Grade(score.Score)
+    return score
+}
+
+// Explain describes each contribution in a line.
+func (s Score) Explain() []string {
+    lines := []string{}
+    for _, c := range s.Breakdown {
+        lines = append(lines, fmt.Sprintf("%s (%s, weight %g): %d issue(s), worst on %d of %d -> -%.1f",
+            c.Category, c.Severity, c.Weight, c.Issues, c.Charged, s.Units, c.Points))
+    }
+    return lines
+}
+
+func round(value float64) float64 {
+    return math.Round(value*10) / 10
+}
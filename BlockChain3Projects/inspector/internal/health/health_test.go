@@ -0,0 +1,51 @@
+package health
+
+import "testing"
+
+func TestScoreChargesWorstIssuePerUnit(t *testing.T) {
+    model := DefaultModel()
+    score := model.Score(10, []Issue{
+        {Unit: 1, Category: "bad_hash", Severity: "critical"},
+        {Unit: 1, Category: "prev_hash", Severity: "critical"},
+        {Unit: 1, Category: "empty_block", Severity: "warning"},
+        {Unit: 4, Category: "empty_block", Severity: "warning"},
+    })
+    if score.Score != 89 || score.Grade != "GOOD" || score.Affected != 2 {
+        t.Errorf("Expected 89 (GOOD) over 2 affected units, got %+v", score)
+    }
+    if score.Breakdown[0].Category != "bad_hash" || score.Breakdown[0].Points != 10 || score.Breakdown[2].Charged != 0 {
+        t.Errorf("Expected bad_hash to cost 10 points and prev_hash on the same block nothing, got %+v", score.Breakdown)
+    }
+
+    model.RuleWeights["empty_block"] = 0
+    model.Grades = []Grade{{"PASS", 80}, {"FAIL", 0}}
+    if score := model.Score(10, []Issue{{Unit: 4, Category: "empty_block", Severity: "warning"}}); score.Score != 100 || score.Grade != "PASS" {
+        t.Errorf("Expected a zero-weight rule to cost nothing, got %+v", score)
+    }
+}
+
+func TestScoreChargesSpansPerUnit(t *testing.T) {
+    model := DefaultModel()
+    score := model.Score(100, []Issue{
+        {Unit: 10, Span: 20, Category: "missing_block", Severity: "error"},
+        {Unit: 10, Category: "chain_spec", Severity: "critical"},
+        {Unit: 50, Category: "empty_block", Severity: "warning"},
+    })
+    if score.Affected != 21 || score.Breakdown[0].Category != "missing_block" || score.Breakdown[0].Charged != 19 {
+        t.Errorf("Expected the gap charged for 19 heights and the checkpoint inside it once, got %+v", score)
+    }
+    if score := model.Score(10, []Issue{{Unit: 0, Span: 1 << 40, Category: "missing_block", Severity: "error"}}); score.Units != 1<<40 || score.Affected != 1<<40 {
+        t.Errorf("Expected a huge gap to count without listing its heights, got %+v", score)
+    }
+}
+
+func TestValidateModel(t *testing.T) {
+    model := DefaultModel()
+    model.SeverityWeights["error"] = 2
+    if model.Validate() == nil {
+        t.Error("Expected a weight above 1 to be rejected")
+    }
+    if (Model{}).Validate() == nil {
+        t.Error("Expected a model without grades to be rejected")
+    }
+}
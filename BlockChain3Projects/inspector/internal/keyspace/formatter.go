@@ -0,0 +1,70 @@
+package keyspace
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+)
+
+// maxTextBlocks caps each block list in text output; --json has them all.
+const maxTextBlocks = 50
+
+func OutputReport(report *Report, jsonMode bool) {
+    if jsonMode {
+        jsonData, _ := json.MarshalIndent(report, "", "  ")
+        fmt.Println(string(jsonData))
+    } else {
+        outputReportText(report)
+    }
+}
+
+func outputReportText(report *Report) {
+    fmt.Println("\n" + strings.Repeat("═", 66))
+    fmt.Println("KEYSPACE ANALYSIS")
+    fmt.Println(strings.Repeat("═", 66))
+
+    fmt.Printf("\n📊 OVERVIEW:\n")
+    fmt.Printf("  Stored Blocks:     %d (highest %d)\n", report.Stored, report.Highest)
+    fmt.Printf("  Unreadable:        %d\n", len(report.Unreadable))
+    fmt.Printf("  Segments:          %d (%d detached)\n", len(report.Segments), report.Detached())
+    fmt.Printf("  Orphans:           %d\n", len(report.Orphans))
+    fmt.Printf("  Side-Chain Blocks: %d\n", len(report.SideChain))
+
+    fmt.Println("\n🧩 SEGMENTS:")
+    for i, s := range report.Segments {
+        if i == maxTextBlocks {
+            fmt.Printf("  ... and %d more (use --json for all)\n", len(report.Segments)-i)
+            break
+        }
+        kind := s.Kind
+        if s.Kind == Attached {
+            kind = fmt.Sprintf("attached to %d", s.ParentHeight)
+        }
+        fmt.Printf("  %8d..%-8d %8d block(s)  %s\n", s.From, s.To, s.Blocks, kind)
+    }
+
+    printBlocks("👻 ORPHANS (parent stored nowhere)", report.Orphans)
+    printBlocks("🔀 SIDE CHAIN (parent at another height)", report.SideChain)
+    if len(report.Unreadable) > 0 {
+        fmt.Printf("\n⚠️  UNREADABLE: %v\n", report.Unreadable)
+    }
+    fmt.Println(strings.Repeat("═", 66))
+}
+
+func printBlocks(title string, list []Block) {
+    if len(list) == 0 {
+        return
+    }
+    fmt.Printf("\n%s:\n", title)
+    for i, b := range list {
+        if i == maxTextBlocks {
+            fmt.Printf("  ... and %d more (use --json for all)\n", len(list)-i)
+            break
+        }
+        parent := "nowhere"
+        if b.ParentHeight >= 0 {
+            parent = fmt.Sprintf("at %d", b.ParentHeight)
+        }
+        fmt.Printf("  #%-8d %s  (prev %s, %s)\n", b.Height, b.Hash, b.PrevHash, parent)
+    }
+}
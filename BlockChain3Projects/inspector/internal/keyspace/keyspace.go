@@ -0,0 +1,151 @@
+// Package keyspace maps out everything stored in a database, however far
+// apart: the runs of consecutive heights (segments) and the blocks whose
+// parent is not the block below them.
+package keyspace
+
+import (
+    "sort"
+
+    "inspector/internal/db"
+)
+
+// Segment kinds: a segment starts at genesis, or its first block's parent
+// is stored elsewhere (attached) or nowhere (orphaned). A first block that
+// cannot be decoded leaves the kind unknown.
+const (
+    Genesis  = "genesis"
+    Attached = "attached"
+    Orphaned = "orphaned"
+    Unknown  = "unknown"
+)
+
+// Segment is a run of consecutive stored heights. ParentHeight is where the
+// first block's parent is stored, -1 if nowhere.
+type Segment struct {
+    From         int    `json:"from"`
+    To           int    `json:"to"`
+    Blocks       int    `json:"blocks"`
+    Kind         string `json:"kind"`
+    FirstHash    string `json:"first_hash,omitempty"`
+    LastHash     string `json:"last_hash,omitempty"`
+    ParentHeight int    `json:"parent_height"`
+}
+
+// Detached reports whether the segment is cut off from genesis by a gap.
+func (s Segment) Detached() bool {
+    return s.From > 0
+}
+
+// Block is a block whose parent is not the block stored below it.
+// ParentHeight is where the parent is stored, -1 if nowhere.
+type Block struct {
+    Height       int    `json:"height"`
+    Hash         string `json:"hash"`
+    PrevHash     string `json:"prev_hash"`
+    ParentHeight int    `json:"parent_height"`
+}
+
+// Report is the layout of a database's keyspace. Orphans have a parent
+// stored nowhere; SideChain blocks have one stored at another height than
+// the one below them.
+type Report struct {
+    Stored     int       `json:"stored"`
+    Highest    int       `json:"highest"`
+    Unreadable []int     `json:"unreadable"`
+    Segments   []Segment `json:"segments"`
+    Orphans    []Block   `json:"orphans"`
+    SideChain  []Block   `json:"side_chain"`
+}
+
+// Detached counts the segments cut off from genesis.
+func (r *Report) Detached() int {
+    count := 0
+    for _, segment := range r.Segments {
+        if segment.Detached() {
+            count++
+        }
+    }
+    return count
+}
+
+// Analyze reads every stored block through the source's hash index.
+// Genesis is never an orphan; its parent is checked against the chain spec
+// by the scan rules instead.
+func Analyze(source db.BlockSource) (*Report, error) {
+    index, err := db.IndexOf(source)
+    if err != nil {
+        return nil, err
+    }
+
+    entries := index.Entries()
+    byHeight := make(map[int]db.IndexEntry, len(entries))
+    heights := make([]int, 0, len(entries))
+    for _, entry := range entries {
+        byHeight[entry.Height] = entry
+        heights = append(heights, entry.Height)
+    }
+    heights = append(heights, index.Unreadable()...)
+    sort.Ints(heights)
+
+    report := &Report{
+        Stored:     len(heights),
+        Highest:    -1,
+        Unreadable: append([]int{}, index.Unreadable()...),
+        Segments:   []Segment{},
+        Orphans:    []Block{},
+        SideChain:  []Block{},
+    }
+    if len(heights) > 0 {
+        report.Highest = heights[len(heights)-1]
+    }
+
+    // parentOf finds where a block's parent is stored, preferring the
+    // height below it.
+    parentOf := func(entry db.IndexEntry) int {
+        parents := index.Lookup(entry.PrevHash)
+        for _, parent := range parents {
+            if parent.Height == entry.Height-1 {
+                return parent.Height
+            }
+        }
+        if len(parents) > 0 {
+            return parents[0].Height
+        }
+        return -1
+    }
+
+    for i, height := range heights {
+        entry, readable := byHeight[height]
+        if i == 0 || heights[i-1] != height-1 {
+            segment := Segment{From: height, Kind: Unknown, ParentHeight: -1}
+            switch {
+            case height == 0:
+                segment.Kind = Genesis
+            case readable:
+                segment.ParentHeight = parentOf(entry)
+                segment.Kind = Attached
+                if segment.ParentHeight < 0 {
+                    segment.Kind = Orphaned
+                }
+            }
+            segment.FirstHash = entry.Hash
+            report.Segments = append(report.Segments, segment)
+        }
+        segment := &report.Segments[len(report.Segments)-1]
+        segment.To, segment.LastHash = height, entry.Hash
+        segment.Blocks++
+
+        if !readable || height == 0 {
+            continue
+        }
+        parent := parentOf(entry)
+        block := Block{Height: height, Hash: entry.Hash, PrevHash: entry.PrevHash, ParentHeight: parent}
+        switch {
+        case parent < 0:
+            report.Orphans = append(report.Orphans, block)
+        case parent != height-1:
+            report.SideChain = append(report.SideChain, block)
+        }
+    }
+    return report, nil
+}
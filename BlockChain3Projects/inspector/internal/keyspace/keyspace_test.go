@@ -0,0 +1,52 @@
+package keyspace
+
+import (
+    "testing"
+
+    "inspector/internal/blocks"
+    "inspector/internal/db"
+)
+
+func TestAnalyzeFindsSegmentsOrphansAndSideChains(t *testing.T) {
+    source, _ := db.NewMemorySource([]*blocks.Block{
+        {Height: 0, Hash: "a0", PrevHash: "0"},
+        {Height: 1, Hash: "a1", PrevHash: "a0"},
+        {Height: 2, Hash: "a2", PrevHash: "a1"},
+        {Height: 3, Hash: "b3", PrevHash: "a0"},
+        {Height: 500, Hash: "c500", PrevHash: "a2"},
+        {Height: 501, Hash: "c501", PrevHash: "c500"},
+        {Height: 9000, Hash: "d9000", PrevHash: "gone"},
+    })
+    source.PutRaw(9001, []byte("{corrupt"))
+
+    report, err := Analyze(source)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if report.Stored != 8 || report.Highest != 9001 || len(report.Unreadable) != 1 {
+        t.Errorf("Expected 8 stored heights up to 9001, got %+v", report)
+    }
+
+    kinds := []string{Genesis, Attached, Orphaned}
+    if len(report.Segments) != 3 || report.Detached() != 2 {
+        t.Fatalf("Expected 3 segments, 2 detached, got %+v", report.Segments)
+    }
+    for i, segment := range report.Segments {
+        if segment.Kind != kinds[i] {
+            t.Errorf("Segment %d: expected %s, got %+v", i, kinds[i], segment)
+        }
+    }
+    if s := report.Segments[1]; s.From != 500 || s.To != 501 || s.ParentHeight != 2 {
+        t.Errorf("Expected segment 500..501 attached to 2, got %+v", s)
+    }
+    if s := report.Segments[2]; s.Blocks != 2 || s.LastHash != "" {
+        t.Errorf("Expected the orphaned segment to end in the unreadable block, got %+v", s)
+    }
+
+    if len(report.Orphans) != 1 || report.Orphans[0].Height != 9000 {
+        t.Errorf("Expected 9000 to be the only orphan, got %+v", report.Orphans)
+    }
+    if len(report.SideChain) != 2 || report.SideChain[0].Height != 3 || report.SideChain[1].ParentHeight != 2 {
+        t.Errorf("Expected 3 and 500 on side chains, got %+v", report.SideChain)
+    }
+}
@@ -2,12 +2,14 @@ package rpc
 
 import (
     "encoding/json"
+    "errors"
     "fmt"
     "io"
     "net/http"
     "time"
 
     "inspector/internal/blocks"
+    "inspector/internal/db"
 )
 
 type Client struct {
@@ -25,6 +27,20 @@ func NewClient(baseURL string) *Client {
 }
 
 func (c *Client) FetchBlock(height int) (*blocks.Block, error) {
+    body, err := c.FetchBlockRaw(height)
+    if err != nil {
+        return nil, err
+    }
+    
+    var block blocks.Block
+    if err := json.Unmarshal(body, &block); err != nil {
+        return nil, fmt.Errorf("failed to parse block: %w", err)
+    }
+    
+    return &block, nil
+}
+
+func (c *Client) FetchBlockRaw(height int) ([]byte, error) {
     url := fmt.Sprintf("%s/block/%d", c.baseURL, height)
     
     resp, err := c.client.Get(url)
@@ -33,6 +49,10 @@ func (c *Client) FetchBlock(height int) (*blocks.Block, error) {
     }
     defer resp.Body.Close()
     
+    if resp.StatusCode == http.StatusNotFound {
+        return nil, db.ErrBlockNotFound
+    }
+    
     if resp.StatusCode != http.StatusOK {
         body, _ := io.ReadAll(resp.Body)
         return nil, fmt.Errorf("RPC error: status %d, body: %s", resp.StatusCode, string(body))
@@ -43,12 +63,54 @@ func (c *Client) FetchBlock(height int) (*blocks.Block, error) {
         return nil, fmt.Errorf("failed to read response: %w", err)
     }
     
-    var block blocks.Block
-    if err := json.Unmarshal(body, &block); err != nil {
-        return nil, fmt.Errorf("failed to parse block: %w", err)
+    return body, nil
+}
+
+// LoadBlock, LoadBlockRaw, Tip, IterateBlocks and Close let a live
+// node stand in for a database as a db.BlockSource.
+func (c *Client) LoadBlock(height int) (*blocks.Block, error) {
+    return c.FetchBlock(height)
+}
+
+func (c *Client) LoadBlockRaw(height int) ([]byte, error) {
+    return c.FetchBlockRaw(height)
+}
+
+// Tip trusts the node's reported height; gaps cannot be seen over RPC.
+func (c *Client) Tip() (db.ChainTip, error) {
+    health, err := c.FetchHealth()
+    if err != nil {
+        return db.ChainTip{Highest: -1}, err
     }
-    
-    return &block, nil
+    return db.ChainTip{Highest: health.Height}, nil
+}
+
+func (c *Client) IterateBlocks(from, to int, visit db.BlockVisitor) error {
+    for height := from; height <= to; height++ {
+        body, err := c.FetchBlockRaw(height)
+        if errors.Is(err, db.ErrBlockNotFound) {
+            continue
+        }
+        if err != nil {
+            return err
+        }
+        
+        var block blocks.Block
+        if err := json.Unmarshal(body, &block); err != nil {
+            if !visit(height, nil, err) {
+                return nil
+            }
+            continue
+        }
+        if !visit(height, &block, nil) {
+            return nil
+        }
+    }
+    return nil
+}
+
+func (c *Client) Close() error {
+    return nil
 }
 
 type HealthResponse struct {
@@ -0,0 +1,374 @@
+package rules
+
+import (
+    "fmt"
+    "strings"
+
+    "inspector/internal/blocks"
+    "inspector/internal/chainspec"
+)
+
+// Rules the scanner reports itself: a stored value that does not decode has
+// no block to hand to a rule, a gap has no value at all, and a block an
+// incremental scan resumes from must still be the one it last verified.
+const (
+    DecodeError       = "decode_error"
+    MissingBlock      = "missing_block"
+    CheckpointChanged = "checkpoint_changed"
+)
+
+func init() {
+    Register(func() Rule { return Meta{DecodeError, "Stored value does not decode as a block", SeverityError} })
+    Register(func() Rule { return hashRule{Meta{"bad_hash", "Block hash does not match its contents", SeverityCritical}} })
+    Register(func() Rule { return merkleRule{Meta{"bad_merkle_root", "Transaction ids are wrong or repeated, or the merkle root does not match the transactions", SeverityCritical}} })
+    Register(func() Rule { return missingSignatureRule{Meta{"missing_signature", "Block is unsigned although a validator set is configured", SeverityError}} })
+    Register(func() Rule { return signatureRule{Meta{"invalid_signature", "Block signature does not verify", SeverityCritical}} })
+    Register(func() Rule { return proposerRule{Meta{"unknown_proposer", "Proposer is not a validator active at the block height", SeverityError}} })
+    Register(func() Rule { return workRule{Meta{"insufficient_work", "Block hash does not meet its declared difficulty", SeverityCritical}} })
+    Register(func() Rule {
+        return &difficultyRule{Meta: Meta{"difficulty", "Difficulty differs from the pow retarget schedule", SeverityError}, window: map[int]int64{}}
+    })
+    Register(func() Rule { return specRule{Meta{"chain_spec", "Genesis or checkpoint hash differs from the chain spec", SeverityCritical}} })
+    Register(func() Rule { return futureRule{Meta{"timestamp_future", "Timestamp further ahead of the scan than the policy's clock_skew", SeverityWarning}} })
+    Register(func() Rule { return pastRule{Meta{"timestamp_past", "Timestamp before the policy's min_timestamp (default ten years ago)", SeverityWarning}} })
+    Register(func() Rule { return timestampOrderRule{Meta{"timestamp_not_increasing", "Timestamp not after the previous block's", SeverityWarning}} })
+    Register(func() Rule { return &medianTimeRule{Meta: Meta{"median_time_past", "Timestamp not after the median of the preceding blocks", SeverityWarning}} })
+    Register(func() Rule { return &intervalRule{Meta: Meta{"interval_outlier", "Block interval far longer than the expected interval", SeverityWarning}} })
+    Register(func() Rule { return &clusterRule{Meta: Meta{"timestamp_cluster", "Run of blocks stamped far closer together than expected", SeverityWarning}} })
+    Register(func() Rule { return duplicateRule{Meta{"duplicate_hash", "Block hash already seen at another height", SeverityError}} })
+    Register(func() Rule { return emptyRule{Meta{"empty_block", "Block has no data, unless the policy allows empty blocks", SeverityWarning}} })
+    Register(func() Rule { return prevHashRule{Meta{"prev_hash", "PrevHash does not link to the previous block", SeverityCritical}} })
+    Register(func() Rule { return heightRule{Meta{"height_mismatch", "Block height differs from the height it is stored under", SeverityError}} })
+    Register(func() Rule { return Meta{MissingBlock, "No block stored at a height below the tip", SeverityError} })
+    Register(func() Rule { return orderRule{Meta{"out_of_order", "Block height not above the previous block's", SeverityError}} })
+    Register(func() Rule { return segmentRule{Meta{"detached_segment", "Stored blocks resume after a gap, cut off from the chain below", SeverityWarning}} })
+    Register(func() Rule {
+        return &parentRule{Meta: Meta{"orphan_block", "Parent hash is not stored at any height", SeverityError}, orphans: true}
+    })
+    Register(func() Rule {
+        return &parentRule{Meta: Meta{"side_chain_block", "Parent is stored at another height than the block below", SeverityWarning}}
+    })
+    Register(func() Rule { return Meta{CheckpointChanged, "Block at the scan checkpoint changed since the last scan", SeverityCritical} })
+}
+
+type hashRule struct{ Meta }
+
+func (r hashRule) CheckBlock(ctx *Context, e Entry) []Finding {
+    version := e.Block.EffectiveHashVersion(ctx.HashVersion)
+    computed, err := blocks.ComputeBlockHashWith(e.Block, version, ctx.HashAlgorithm)
+    if err != nil {
+        return found(e, "", "", "Bad hash - %v", err)
+    }
+    if e.Block.Hash != computed {
+        algorithm := ctx.HashAlgorithm
+        if algorithm == "" {
+            algorithm = blocks.DefaultHashAlgorithm
+        }
+        return found(e, computed, e.Block.Hash, "Bad hash (v%d/%s)", version, algorithm)
+    }
+    return nil
+}
+
+// merkleRule skips legacy blocks that carry neither transactions nor a root.
+// A repeated transaction id is rejected even when the root matches: an odd
+// level pairs its last node with itself, so appending a copy of the last
+// transaction leaves the root unchanged (CVE-2012-2459).
+type merkleRule struct{ Meta }
+
+func (r merkleRule) CheckBlock(ctx *Context, e Entry) []Finding {
+    block := e.Block
+    if len(block.Transactions) == 0 && block.MerkleRoot == "" {
+        return nil
+    }
+    seen := make(map[string]int, len(block.Transactions))
+    for j, tx := range block.Transactions {
+        if id := blocks.ComputeTxID(tx); tx.ID != id {
+            return found(e, id, tx.ID, "Transaction %d has a bad id", j)
+        }
+        if first, ok := seen[tx.ID]; ok {
+            return found(e, "", tx.ID, "Transaction %d repeats transaction %d", j, first)
+        }
+        seen[tx.ID] = j
+    }
+    if root := blocks.ComputeMerkleRoot(block.Transactions); root != block.MerkleRoot {
+        return found(e, root, block.MerkleRoot, "Merkle root mismatch")
+    }
+    return nil
+}
+
+type missingSignatureRule struct{ Meta }
+
+func (r missingSignatureRule) CheckBlock(ctx *Context, e Entry) []Finding {
+    if ctx.Validators != nil && e.Block.Signature == "" {
+        return found(e, "", "", "Missing signature")
+    }
+    return nil
+}
+
+// signatureRule checks every signature present, with or without a
+// validator set.
+type signatureRule struct{ Meta }
+
+func (r signatureRule) CheckBlock(ctx *Context, e Entry) []Finding {
+    if e.Block.Signature == "" {
+        return nil
+    }
+    if err := blocks.VerifyBlockSignature(e.Block); err != nil {
+        return found(e, "", "", "Invalid signature - %v", err)
+    }
+    return nil
+}
+
+type proposerRule struct{ Meta }
+
+func (r proposerRule) CheckBlock(ctx *Context, e Entry) []Finding {
+    if ctx.Validators == nil || e.Block.Proposer == "" {
+        return nil
+    }
+    v := ctx.Validators.Lookup(e.Block.Proposer)
+    if v == nil {
+        return found(e, "", e.Block.Proposer, "Unknown proposer %s", shortKey(e.Block.Proposer))
+    }
+    if !v.ActiveAt(e.Block.Height) {
+        return found(e, "", e.Block.Proposer, "Proposer %s not active at this height", v.Label())
+    }
+    return nil
+}
+
+type workRule struct{ Meta }
+
+func (r workRule) CheckBlock(ctx *Context, e Entry) []Finding {
+    if !blocks.MeetsDifficulty(e.Block.Hash, e.Block.Difficulty) {
+        return found(e, fmt.Sprintf("%d zero bits", e.Block.Difficulty), fmt.Sprintf("%d zero bits", blocks.LeadingZeroBits(e.Block.Hash)),
+            "Hash does not meet difficulty %d", e.Block.Difficulty)
+    }
+    return nil
+}
+
+// difficultyRule replays the retarget schedule. It needs the previous block
+// and, at a retarget, the timestamps of the whole window; across gaps it is
+// skipped.
+type difficultyRule struct {
+    Meta
+    window map[int]int64
+}
+
+func (r *difficultyRule) CheckPair(ctx *Context, prev *Entry, e Entry) []Finding {
+    pow := ctx.Pow
+    if pow == nil {
+        return nil
+    }
+    i := e.Height
+    defer func() {
+        r.window[i] = e.Block.Timestamp
+        delete(r.window, i-pow.RetargetWindow)
+    }()
+
+    prevDifficulty := 0
+    if prev != nil {
+        prevDifficulty = prev.Block.Difficulty
+    }
+    start, haveStart := r.window[i-pow.RetargetWindow]
+    end, haveEnd := r.window[i-1]
+    checkable := i == 0 || prev != nil && prev.Block.Height == i-1
+    if pow.IsRetarget(i) {
+        checkable = checkable && haveStart && haveEnd
+    }
+    if !checkable {
+        return nil
+    }
+    if expected := pow.ExpectedDifficulty(i, prevDifficulty, start, end); e.Block.Difficulty != expected {
+        return found(e, fmt.Sprint(expected), fmt.Sprint(e.Block.Difficulty), "Difficulty %d, expected %d", e.Block.Difficulty, expected)
+    }
+    return nil
+}
+
+func (r *difficultyRule) Lookback(ctx *Context) int {
+    if ctx.Pow == nil {
+        return 0
+    }
+    return ctx.Pow.RetargetWindow
+}
+
+// specRule holds blocks against the chain spec, and reports a genesis or
+// checkpoint height the scan found no block at.
+type specRule struct{ Meta }
+
+func (r specRule) CheckBlock(ctx *Context, e Entry) []Finding {
+    if ctx.Spec == nil {
+        return nil
+    }
+    return SpecFindings(ctx.Spec.CheckBlock(e.Height, e.Block.Hash))
+}
+
+func (r specRule) Finish(ctx *Context) []Finding {
+    if ctx.Spec == nil {
+        return nil
+    }
+    var findings []Finding
+    for _, gap := range ctx.Missing {
+        for _, height := range ctx.Spec.Heights() {
+            if gap.Contains(height) {
+                findings = append(findings, SpecFindings(ctx.Spec.Missing(height))...)
+            }
+        }
+    }
+    return findings
+}
+
+// SpecFindings turns chain spec mismatches into findings; the block hash is
+// the one found at the height, if any.
+func SpecFindings(mismatches []chainspec.Mismatch) []Finding {
+    var findings []Finding
+    for _, m := range mismatches {
+        message := m.Kind + " hash mismatch"
+        if m.Actual == "" {
+            message = m.Kind + " block missing"
+        }
+        findings = append(findings, Finding{
+            Height:    m.Height,
+            BlockHash: m.Actual,
+            Expected:  m.Expected,
+            Actual:    m.Actual,
+            Message:   message,
+        })
+    }
+    return findings
+}
+
+type futureRule struct{ Meta }
+
+func (r futureRule) CheckBlock(ctx *Context, e Entry) []Finding {
+    if limit := ctx.Now + ctx.Policy.ClockSkew; e.Block.Timestamp > limit {
+        return found(e, fmt.Sprintf("<= %d", limit), fmt.Sprint(e.Block.Timestamp), "Timestamp in future")
+    }
+    return nil
+}
+
+type pastRule struct{ Meta }
+
+func (r pastRule) CheckBlock(ctx *Context, e Entry) []Finding {
+    if min := ctx.Policy.MinTimestamp; e.Block.Timestamp < min {
+        return found(e, fmt.Sprintf(">= %d", min), fmt.Sprint(e.Block.Timestamp), "Timestamp too old")
+    }
+    return nil
+}
+
+type timestampOrderRule struct{ Meta }
+
+func (r timestampOrderRule) CheckPair(ctx *Context, prev *Entry, e Entry) []Finding {
+    if prev != nil && e.Block.Timestamp <= prev.Block.Timestamp {
+        return found(e, fmt.Sprintf("> %d", prev.Block.Timestamp), fmt.Sprint(e.Block.Timestamp), "Timestamp not increasing")
+    }
+    return nil
+}
+
+type duplicateRule struct{ Meta }
+
+func (r duplicateRule) CheckPair(ctx *Context, prev *Entry, e Entry) []Finding {
+    if first, exists := ctx.SeenAt(e.Block.Hash); exists && first != e.Height {
+        return found(e, "", "", "Duplicates hash from Block %d", first)
+    }
+    ctx.see(e)
+    return nil
+}
+
+type emptyRule struct{ Meta }
+
+func (r emptyRule) CheckBlock(ctx *Context, e Entry) []Finding {
+    if !ctx.Policy.AllowEmptyBlocks && strings.TrimSpace(e.Block.Data) == "" {
+        return found(e, "", "", "Empty block")
+    }
+    return nil
+}
+
+// prevHashRule checks the genesis block against the spec's prev hash (or
+// "0") and every other block against the block read before it.
+type prevHashRule struct{ Meta }
+
+func (r prevHashRule) CheckPair(ctx *Context, prev *Entry, e Entry) []Finding {
+    if e.Height == 0 {
+        if genesisPrev := ctx.Spec.GenesisPrev(); e.Block.PrevHash != genesisPrev {
+            return found(e, genesisPrev, e.Block.PrevHash, "Invalid genesis prevHash")
+        }
+    } else if prev != nil && e.Block.PrevHash != prev.Block.Hash {
+        return found(e, prev.Block.Hash, e.Block.PrevHash, "PrevHash linkage broken")
+    }
+    return nil
+}
+
+type heightRule struct{ Meta }
+
+func (r heightRule) CheckBlock(ctx *Context, e Entry) []Finding {
+    if e.Block.Height != e.Height {
+        return found(e, fmt.Sprint(e.Height), fmt.Sprint(e.Block.Height), "Height mismatch")
+    }
+    return nil
+}
+
+type orderRule struct{ Meta }
+
+func (r orderRule) CheckPair(ctx *Context, prev *Entry, e Entry) []Finding {
+    if prev != nil && e.Block.Height <= prev.Block.Height {
+        return found(e, fmt.Sprintf("> %d", prev.Block.Height), fmt.Sprint(e.Block.Height), "Out of order")
+    }
+    return nil
+}
+
+// segmentRule reports the first block after each gap once, however long
+// the gap.
+type segmentRule struct{ Meta }
+
+func (r segmentRule) CheckPair(ctx *Context, prev *Entry, e Entry) []Finding {
+    if len(ctx.Missing) == 0 {
+        return nil
+    }
+    gap := ctx.Missing[len(ctx.Missing)-1]
+    if gap.To != e.Height-1 {
+        return nil
+    }
+    return found(e, "", "", "Segment starts after a gap at heights %d..%d", gap.From, gap.To)
+}
+
+// parentRule looks up the parent of every block whose PrevHash is not the
+// block below it among all blocks the scan saw. With orphans set it reports
+// parents stored nowhere, which partial scans cannot tell; otherwise
+// parents stored at another height.
+type parentRule struct {
+    Meta
+    orphans bool
+    pending []Entry
+}
+
+func (r *parentRule) CheckPair(ctx *Context, prev *Entry, e Entry) []Finding {
+    if prev != nil {
+        ctx.see(*prev)
+    }
+    ctx.see(e)
+    linked := prev != nil && prev.Height == e.Height-1 && prev.Block.Hash == e.Block.PrevHash
+    if e.Height > 0 && !linked {
+        r.pending = append(r.pending, e)
+    }
+    return nil
+}
+
+func (r *parentRule) Finish(ctx *Context) []Finding {
+    var findings []Finding
+    for _, e := range r.pending {
+        parent, ok := ctx.SeenAt(e.Block.PrevHash)
+        switch {
+        case !ok && r.orphans && !ctx.Partial:
+            findings = append(findings, found(e, "", e.Block.PrevHash, "Orphan block: parent is stored nowhere")...)
+        case ok && !r.orphans && parent != e.Height-1:
+            findings = append(findings, found(e, fmt.Sprint(e.Height-1), fmt.Sprint(parent), "Side-chain block: parent stored at height %d", parent)...)
+        }
+    }
+    return findings
+}
+
+func shortKey(key string) string {
+    if len(key) > 16 {
+        return key[:16] + "..."
+    }
+    return key
+}
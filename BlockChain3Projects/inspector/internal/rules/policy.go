@@ -0,0 +1,169 @@
+package rules
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+
+    "inspector/internal/health"
+)
+
+// Policy holds the thresholds scan rules judge blocks by. ClockSkew is how
+// many seconds ahead of the scan a timestamp may be. MinTimestamp is the
+// earliest timestamp allowed; 0 means ten years before the scan. Severity
+// overrides the severity of rules by id. FailOn is the lowest severity that
+// fails a scan (default error); findings below it are reported but leave
+// the chain healthy. Timestamps tunes the statistical timestamp rules.
+// Health weighs findings into the health score and grades it.
+type Policy struct {
+    ClockSkew        int64               `json:"clock_skew"`
+    MinTimestamp     int64               `json:"min_timestamp"`
+    AllowEmptyBlocks bool                `json:"allow_empty_blocks"`
+    Severity         map[string]Severity `json:"severity,omitempty"`
+    FailOn           Severity            `json:"fail_on"`
+    Timestamps       TimestampPolicy     `json:"timestamps"`
+    Health           health.Model        `json:"health"`
+}
+
+// TimestampPolicy tunes the statistical timestamp rules. MedianWindow is
+// how many blocks the median-time-past is taken over. Intervals are judged
+// on a log scale by IntervalMethod "mad" (median absolute deviation) or
+// "zscore" against the last IntervalWindow intervals, and are outliers
+// when longer by IntervalThreshold deviations. ClusterBlocks or more blocks
+// in a row less than ClusterFraction of the expected interval apart form a
+// cluster.
+type TimestampPolicy struct {
+    MedianWindow      int     `json:"median_window"`
+    IntervalMethod    string  `json:"interval_method"`
+    IntervalThreshold float64 `json:"interval_threshold"`
+    IntervalWindow    int     `json:"interval_window"`
+    ClusterBlocks     int     `json:"cluster_blocks"`
+    ClusterFraction   float64 `json:"cluster_fraction"`
+}
+
+const tenYears = 10 * 365 * 24 * 60 * 60
+
+var severities = []Severity{SeverityInfo, SeverityWarning, SeverityError, SeverityCritical}
+
+// DefaultPolicy is the policy scans use when none is given.
+func DefaultPolicy() *Policy {
+    return &Policy{
+        ClockSkew: 300,
+        FailOn:    SeverityError,
+        Timestamps: TimestampPolicy{
+            MedianWindow:      11,
+            IntervalMethod:    "mad",
+            IntervalThreshold: 3.5,
+            IntervalWindow:    100,
+            ClusterBlocks:     6,
+            ClusterFraction:   0.1,
+        },
+        Health: health.DefaultModel(),
+    }
+}
+
+// LoadPolicy reads a policy file; settings it leaves out keep their
+// defaults.
+func LoadPolicy(path string) (*Policy, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read policy: %w", err)
+    }
+
+    policy := DefaultPolicy()
+    if err := json.Unmarshal(data, policy); err != nil {
+        return nil, fmt.Errorf("failed to parse policy: %w", err)
+    }
+    if err := policy.Validate(); err != nil {
+        return nil, fmt.Errorf("policy %s: %w", path, err)
+    }
+    return policy, nil
+}
+
+// Validate checks the thresholds and that severity overrides name known
+// rules and levels.
+func (p *Policy) Validate() error {
+    if p.ClockSkew < 0 {
+        return fmt.Errorf("clock_skew must not be negative")
+    }
+    if _, err := ParseSeverity(string(p.FailOn)); err != nil {
+        return fmt.Errorf("fail_on: %w", err)
+    }
+    for id, level := range p.Severity {
+        if err := checkIDs([]string{id}); err != nil {
+            return err
+        }
+        if _, err := ParseSeverity(string(level)); err != nil {
+            return fmt.Errorf("rule %s: %w", id, err)
+        }
+    }
+    if err := p.Timestamps.validate(); err != nil {
+        return fmt.Errorf("timestamps: %w", err)
+    }
+    if err := p.Health.Validate(); err != nil {
+        return fmt.Errorf("health: %w", err)
+    }
+    return nil
+}
+
+func (t TimestampPolicy) validate() error {
+    switch {
+    case t.MedianWindow < 1 || t.IntervalWindow < 1:
+        return fmt.Errorf("median_window and interval_window must be at least 1")
+    case t.IntervalMethod != "mad" && t.IntervalMethod != "zscore":
+        return fmt.Errorf("unknown interval_method %q (mad, zscore)", t.IntervalMethod)
+    case t.IntervalThreshold <= 0:
+        return fmt.Errorf("interval_threshold must be positive")
+    case t.ClusterBlocks < 2:
+        return fmt.Errorf("cluster_blocks must be at least 2")
+    case t.ClusterFraction <= 0 || t.ClusterFraction > 1:
+        return fmt.Errorf("cluster_fraction must be above 0 and at most 1")
+    }
+    return nil
+}
+
+// ParseSeverity returns the severity named by level.
+func ParseSeverity(level string) (Severity, error) {
+    for _, s := range severities {
+        if string(s) == level {
+            return s, nil
+        }
+    }
+    return "", fmt.Errorf("unknown severity %q (info, warning, error, critical)", level)
+}
+
+// AtLeast reports whether s is level or more severe.
+func (s Severity) AtLeast(level Severity) bool {
+    rank := func(s Severity) int {
+        for i, known := range severities {
+            if known == s {
+                return i
+            }
+        }
+        return -1
+    }
+    return rank(s) >= rank(level)
+}
+
+// Fails reports whether a finding of severity fails a scan.
+func (p *Policy) Fails(severity Severity) bool {
+    return severity.AtLeast(p.FailOn)
+}
+
+// SeverityOf is the severity findings of rule are reported with.
+func (p *Policy) SeverityOf(rule Rule) Severity {
+    if level, ok := p.Severity[rule.ID()]; ok {
+        return level
+    }
+    return rule.Severity()
+}
+
+// Resolve returns a copy of the policy with MinTimestamp worked out for a
+// scan at now, so the copy records exactly what the scan checked against.
+func (p *Policy) Resolve(now int64) Policy {
+    resolved := *p
+    if resolved.MinTimestamp == 0 {
+        resolved.MinTimestamp = now - tenYears
+    }
+    return resolved
+}
@@ -0,0 +1,279 @@
+// Package rules holds the checks the error scanner runs over a chain.
+//
+// A rule has an id, a description and a severity. Block rules look at one
+// block on its own; chain rules see each block together with the one read
+// before it and may keep state across the scan. Rules are registered by id,
+// so a chain-specific check lives in its own package and only has to call
+// Register from an init function.
+package rules
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+
+    "inspector/internal/blocks"
+    "inspector/internal/chainspec"
+    "inspector/internal/db"
+    "inspector/internal/validators"
+)
+
+type Severity string
+
+const (
+    SeverityInfo     Severity = "info"
+    SeverityWarning  Severity = "warning"
+    SeverityError    Severity = "error"
+    SeverityCritical Severity = "critical"
+)
+
+type Rule interface {
+    ID() string
+    Description() string
+    Severity() Severity
+}
+
+// BlockRule checks a single block. It must not depend on earlier blocks or
+// keep state: a parallel scan calls it from several goroutines at once.
+type BlockRule interface {
+    Rule
+    CheckBlock(ctx *Context, block Entry) []Finding
+}
+
+// ChainRule checks a block against the previous block the scan decoded,
+// which is nil for the first one. Blocks arrive in height order.
+type ChainRule interface {
+    Rule
+    CheckPair(ctx *Context, prev *Entry, block Entry) []Finding
+}
+
+// Finisher is implemented by rules that report once the scan is over.
+type Finisher interface {
+    Rule
+    Finish(ctx *Context) []Finding
+}
+
+// Lookback is implemented by chain rules that judge a block by a window of
+// blocks before it. A scan starting partway up the chain first shows them
+// Lookback blocks below its start, so they judge its blocks as a full scan
+// would.
+type Lookback interface {
+    ChainRule
+    Lookback(ctx *Context) int
+}
+
+// Entry is a decoded block and the height it is stored under, which a
+// broken block may not agree with.
+type Entry struct {
+    Height int
+    Block  *blocks.Block
+}
+
+// Finding is one problem found at a height. Rules fill in the block and
+// evidence; the scanner stamps RuleID and Severity. Expected and Actual are
+// left empty when a check has no single value to show. Node names the node
+// a comparison finding belongs to, when it belongs to one. Span is set when
+// the finding covers that many heights from Height on, as a gap does.
+type Finding struct {
+    RuleID    string   `json:"rule_id"`
+    Severity  Severity `json:"severity"`
+    Height    int      `json:"height"`
+    Span      int      `json:"span,omitempty"`
+    Node      string   `json:"node,omitempty"`
+    BlockHash string   `json:"block_hash,omitempty"`
+    Expected  string   `json:"expected,omitempty"`
+    Actual    string   `json:"actual,omitempty"`
+    Message   string   `json:"message"`
+}
+
+// String renders the finding on one line for text output.
+func (f Finding) String() string {
+    s := fmt.Sprintf("Block %d: %s", f.Height, f.Message)
+    if f.Expected != "" || f.Actual != "" {
+        s += fmt.Sprintf(" (expected %s, got %s)", orNone(f.Expected), orNone(f.Actual))
+    }
+    return s
+}
+
+func orNone(value string) string {
+    if value == "" {
+        return "none"
+    }
+    return value
+}
+
+// Count returns how many findings each rule id has.
+func Count(findings []Finding) map[string]int {
+    counts := make(map[string]int)
+    for _, f := range findings {
+        counts[f.RuleID]++
+    }
+    return counts
+}
+
+// Context carries the chain settings and scan state rules may consult.
+// Policy is resolved for the scan time. Partial is set when the scan does
+// not see every stored block, so rules needing the whole chain hold back.
+// Missing lists the gaps in height order as the scan finds them; block
+// rules, which may run ahead of the scan, must not read it, nor SeenAt.
+// The chain rules that look blocks up by hash share one index of them,
+// hashes, which like db.HashIndex ignores the case of hex digests.
+type Context struct {
+    HashVersion   int
+    HashAlgorithm string
+    Validators    *validators.Set
+    Pow           *blocks.PowParams
+    Spec          *chainspec.Spec
+    Now           int64
+    Policy        Policy
+    Partial       bool
+    Tip           db.ChainTip
+    Missing       []Gap
+    hashes        map[string]int
+}
+
+// see records the height of a block hash, keeping the first one seen.
+func (c *Context) see(e Entry) {
+    if c.hashes == nil {
+        c.hashes = make(map[string]int)
+    }
+    key := strings.ToLower(e.Block.Hash)
+    if _, ok := c.hashes[key]; !ok {
+        c.hashes[key] = e.Height
+    }
+}
+
+// SeenAt returns the first height the scan has so far seen a block hash at.
+func (c *Context) SeenAt(hash string) (int, bool) {
+    height, ok := c.hashes[strings.ToLower(hash)]
+    return height, ok
+}
+
+// Gap is a run of heights From..To with no block stored.
+type Gap struct {
+    From int
+    To   int
+}
+
+func (g Gap) Contains(height int) bool {
+    return height >= g.From && height <= g.To
+}
+
+// Meta implements Rule and is meant to be embedded in rule types.
+type Meta struct {
+    RuleID string
+    Desc   string
+    Level  Severity
+}
+
+func (m Meta) ID() string          { return m.RuleID }
+func (m Meta) Description() string { return m.Desc }
+func (m Meta) Severity() Severity  { return m.Level }
+
+// found reports a finding about e, with expected and actual values when the
+// check has them.
+func found(e Entry, expected, actual string, format string, args ...interface{}) []Finding {
+    return []Finding{{
+        Height:    e.Height,
+        BlockHash: e.Block.Hash,
+        Expected:  expected,
+        Actual:    actual,
+        Message:   fmt.Sprintf(format, args...),
+    }}
+}
+
+// Factory returns a fresh rule; chain rules get a new instance per scan.
+type Factory func() Rule
+
+var (
+    factories = map[string]Factory{}
+    order     []string
+)
+
+// Register makes a rule available by its id, replacing any earlier rule
+// with the same id.
+func Register(factory Factory) {
+    id := factory().ID()
+    if _, ok := factories[id]; !ok {
+        order = append(order, id)
+    }
+    factories[id] = factory
+}
+
+// Unregister removes the rule registered as id, if there is one.
+func Unregister(id string) {
+    if _, ok := factories[id]; !ok {
+        return
+    }
+    delete(factories, id)
+    for i, registered := range order {
+        if registered == id {
+            order = append(order[:i:i], order[i+1:]...)
+            break
+        }
+    }
+}
+
+// All returns a fresh instance of every registered rule in registration
+// order.
+func All() []Rule {
+    all := make([]Rule, 0, len(order))
+    for _, id := range order {
+        all = append(all, factories[id]())
+    }
+    return all
+}
+
+// New returns a fresh instance of the rule registered as id, or nil if
+// there is none.
+func New(id string) Rule {
+    factory, ok := factories[id]
+    if !ok {
+        return nil
+    }
+    return factory()
+}
+
+// Select returns the rules a scan runs: those in enable, or every rule
+// when enable is empty, minus those in disable.
+func Select(enable, disable []string) ([]Rule, error) {
+    if err := checkIDs(enable); err != nil {
+        return nil, err
+    }
+    if err := checkIDs(disable); err != nil {
+        return nil, err
+    }
+    wanted := make(map[string]bool)
+    for _, id := range enable {
+        wanted[id] = true
+    }
+
+    selected := []Rule{}
+    for _, rule := range All() {
+        if len(enable) > 0 && !wanted[rule.ID()] || contains(disable, rule.ID()) {
+            continue
+        }
+        selected = append(selected, rule)
+    }
+    return selected, nil
+}
+
+func checkIDs(ids []string) error {
+    for _, id := range ids {
+        if _, ok := factories[id]; !ok {
+            known := append([]string(nil), order...)
+            sort.Strings(known)
+            return fmt.Errorf("unknown rule %q (known: %s)", id, strings.Join(known, ", "))
+        }
+    }
+    return nil
+}
+
+func contains(ids []string, id string) bool {
+    for _, candidate := range ids {
+        if candidate == id {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,189 @@
+package rules
+
+import (
+    "math/rand"
+    "testing"
+
+    "inspector/internal/blocks"
+    "inspector/internal/chainspec"
+)
+
+func ids(selected []Rule) map[string]bool {
+    set := make(map[string]bool)
+    for _, rule := range selected {
+        set[rule.ID()] = true
+    }
+    return set
+}
+
+func TestSelectRules(t *testing.T) {
+    all, err := Select(nil, nil)
+    if err != nil || len(all) != len(All()) {
+        t.Fatalf("Expected every rule by default, got %d (%v)", len(all), err)
+    }
+
+    selected, _ := Select([]string{"bad_hash", "prev_hash"}, []string{"prev_hash"})
+    if got := ids(selected); len(got) != 1 || !got["bad_hash"] {
+        t.Errorf("Expected only bad_hash, got %v", got)
+    }
+
+    selected, _ = Select(nil, []string{"empty_block"})
+    if got := ids(selected); got["empty_block"] || len(got) != len(all)-1 {
+        t.Errorf("Expected empty_block disabled, got %v", got)
+    }
+
+    if _, err := Select([]string{"no_such_rule"}, nil); err == nil {
+        t.Error("Expected unknown rule id to fail")
+    }
+
+    Register(func() Rule { return Meta{"test_extra", "Registered by the test", SeverityInfo} })
+    Unregister("test_extra")
+    if _, err := Select([]string{"test_extra"}, nil); err == nil || len(All()) != len(all) {
+        t.Errorf("Expected test_extra gone after Unregister, got %d rules (%v)", len(All()), err)
+    }
+}
+
+func TestChainRulesStartFresh(t *testing.T) {
+    ctx := &Context{}
+    entry := Entry{Height: 0, Block: &blocks.Block{Hash: "aa"}}
+
+    first := factories["duplicate_hash"]().(ChainRule)
+    first.CheckPair(ctx, nil, entry)
+    if findings := first.CheckPair(ctx, &entry, Entry{Height: 1, Block: entry.Block}); len(findings) != 1 {
+        t.Errorf("Expected the repeated hash to be flagged, got %v", findings)
+    }
+
+    fresh := factories["duplicate_hash"]().(ChainRule)
+    if findings := fresh.CheckPair(&Context{}, nil, Entry{Height: 1, Block: entry.Block}); len(findings) != 0 {
+        t.Errorf("Expected a new scan to start empty, got %v", findings)
+    }
+}
+
+func TestHashRulesShareOneIndex(t *testing.T) {
+    ctx := &Context{}
+    chain := []Entry{
+        {Height: 0, Block: &blocks.Block{Hash: "aa", PrevHash: "0"}},
+        {Height: 1, Block: &blocks.Block{Hash: "bb", PrevHash: "aa"}},
+        {Height: 2, Block: &blocks.Block{Hash: "cc", PrevHash: "aa"}},
+    }
+    var findings []Finding
+    checks := []ChainRule{factories["duplicate_hash"]().(ChainRule), factories["orphan_block"]().(ChainRule), factories["side_chain_block"]().(ChainRule)}
+    for i, e := range chain {
+        var prev *Entry
+        if i > 0 {
+            prev = &chain[i-1]
+        }
+        for _, rule := range checks {
+            findings = append(findings, rule.CheckPair(ctx, prev, e)...)
+        }
+    }
+    for _, rule := range checks[1:] {
+        findings = append(findings, rule.(Finisher).Finish(ctx)...)
+    }
+    if len(ctx.hashes) != len(chain) || len(findings) != 1 || findings[0].Height != 2 || findings[0].Actual != "0" {
+        t.Errorf("Expected one index of %d hashes and block 2 on a side chain from 0, got %v, %v", len(chain), ctx.hashes, findings)
+    }
+
+    upper := Entry{Height: 3, Block: &blocks.Block{Hash: "BB", PrevHash: "CC"}}
+    if got := checks[0].CheckPair(ctx, &chain[2], upper); len(got) != 1 || got[0].Message != "Duplicates hash from Block 1" {
+        t.Errorf("Expected BB to duplicate bb, got %v", got)
+    }
+    if height, ok := ctx.SeenAt("CC"); !ok || height != 2 {
+        t.Errorf("Expected SeenAt to ignore case, got %d, %v", height, ok)
+    }
+}
+
+func TestMerkleRuleRejectsRepeatedTransactions(t *testing.T) {
+    txs := []blocks.Transaction{{Sender: "a", Receiver: "b", Amount: 1}, {Sender: "b", Receiver: "c", Amount: 2}, {Sender: "c", Receiver: "a", Amount: 3}}
+    for i := range txs {
+        txs[i].ID = blocks.ComputeTxID(txs[i])
+    }
+    padded := append(append([]blocks.Transaction(nil), txs...), txs[2])
+    if blocks.ComputeMerkleRoot(padded) != blocks.ComputeMerkleRoot(txs) {
+        t.Fatal("Expected the repeated last transaction to keep the root")
+    }
+
+    rule := factories["bad_merkle_root"]().(BlockRule)
+    block := &blocks.Block{Transactions: txs, MerkleRoot: blocks.ComputeMerkleRoot(txs)}
+    if findings := rule.CheckBlock(&Context{}, Entry{Block: block}); len(findings) != 0 {
+        t.Errorf("Expected a clean block, got %v", findings)
+    }
+    block.Transactions = padded
+    if findings := rule.CheckBlock(&Context{}, Entry{Block: block}); len(findings) != 1 {
+        t.Errorf("Expected the repeated transaction to be flagged, got %v", findings)
+    }
+}
+
+// runChain feeds timestamps to a chain rule as consecutive blocks and
+// returns its findings, including those it reports when finishing.
+func runChain(ctx *Context, rule ChainRule, timestamps []int64) []Finding {
+    var findings []Finding
+    var prev *Entry
+    for height, timestamp := range timestamps {
+        entry := Entry{Height: height, Block: &blocks.Block{Height: height, Timestamp: timestamp}}
+        findings = append(findings, rule.CheckPair(ctx, prev, entry)...)
+        prev = &entry
+    }
+    if finisher, ok := rule.(Finisher); ok {
+        findings = append(findings, finisher.Finish(ctx)...)
+    }
+    return findings
+}
+
+func TestTimestampStatistics(t *testing.T) {
+    ctx := &Context{Policy: *DefaultPolicy()}
+    regular := func(n int) []int64 {
+        timestamps := make([]int64, n)
+        for i := range timestamps {
+            timestamps[i] = 1000 + int64(i)*60
+        }
+        return timestamps
+    }
+
+    timestamps := regular(30)
+    timestamps[20] = timestamps[12]
+    findings := runChain(ctx, factories["median_time_past"]().(ChainRule), timestamps)
+    if len(findings) != 1 || findings[0].Height != 20 {
+        t.Errorf("Expected the block stamped back to 12 to fail median-time-past, got %v", findings)
+    }
+
+    timestamps = regular(30)
+    for i := 25; i < 30; i++ {
+        timestamps[i] += 3600
+    }
+    findings = runChain(ctx, factories["interval_outlier"]().(ChainRule), timestamps)
+    if len(findings) != 1 || findings[0].Height != 25 || findings[0].Actual != "3660s" || findings[0].Expected != "<= 84s" {
+        t.Errorf("Expected one outlier interval at 25 with the expected range, got %v", findings)
+    }
+
+    timestamps = regular(40)
+    for i := 21; i < 40; i++ {
+        timestamps[i] = timestamps[20] + int64(i-20)
+    }
+    ctx.Spec = &chainspec.Spec{BlockInterval: 60}
+    findings = runChain(ctx, factories["timestamp_cluster"]().(ChainRule), timestamps)
+    if len(findings) != 1 || findings[0].Height != 20 || findings[0].Actual != "20 blocks in 19s" {
+        t.Errorf("Expected one cluster from 20 to the tip, got %v", findings)
+    }
+}
+
+// Proof-of-work blocks arrive as a Poisson process: intervals are
+// exponential, a tenth of them under a tenth of the mean and one in twenty
+// over three times it. None of that is an anomaly.
+func TestTimestampStatisticsAcceptPowJitter(t *testing.T) {
+    rng := rand.New(rand.NewSource(1))
+    timestamps := make([]int64, 5000)
+    timestamps[0] = 1000
+    for i := 1; i < len(timestamps); i++ {
+        timestamps[i] = timestamps[i-1] + int64(rng.ExpFloat64()*600)
+    }
+
+    for _, spec := range []*chainspec.Spec{nil, {BlockInterval: 600}} {
+        ctx := &Context{Policy: *DefaultPolicy(), Spec: spec}
+        for _, id := range []string{"median_time_past", "interval_outlier", "timestamp_cluster"} {
+            if findings := runChain(ctx, factories[id]().(ChainRule), timestamps); len(findings) != 0 {
+                t.Errorf("Expected %s to accept exponential intervals (spec %v), got %d findings, first %v", id, spec, len(findings), findings[0])
+            }
+        }
+    }
+}
@@ -0,0 +1,207 @@
+package rules
+
+import (
+    "fmt"
+    "math"
+    "sort"
+)
+
+// minIntervals is how many intervals the interval rules collect before
+// judging their spread, or their median when the chain spec sets no
+// block_interval to go by.
+const minIntervals = 10
+
+// intervals keeps the last size block intervals, in seconds.
+type intervals struct {
+    values []float64
+}
+
+func (w *intervals) add(value float64, size int) {
+    w.values = append(w.values, value)
+    if len(w.values) > size {
+        w.values = append(w.values[:0], w.values[len(w.values)-size:]...)
+    }
+}
+
+// expected is the interval blocks should be apart: the chain spec's
+// block_interval, else the median of the recent intervals. ok is false
+// while there are too few intervals to tell.
+func (w *intervals) expected(ctx *Context) (float64, bool) {
+    if ctx.Spec != nil && ctx.Spec.BlockInterval > 0 {
+        return float64(ctx.Spec.BlockInterval), true
+    }
+    if len(w.values) < minIntervals {
+        return 0, false
+    }
+    return median(w.values), true
+}
+
+// median returns the middle value, the upper one of an even count.
+func median(values []float64) float64 {
+    sorted := append([]float64(nil), values...)
+    sort.Float64s(sorted)
+    return sorted[len(sorted)/2]
+}
+
+// consecutive reports whether prev is the block right below e, the only
+// case a timestamp difference is a block interval.
+func consecutive(prev *Entry, e Entry) bool {
+    return prev != nil && prev.Height == e.Height-1
+}
+
+// medianTimeRule requires every timestamp to be after the median of the
+// previous MedianWindow blocks. The window restarts after a gap and blocks
+// are only judged on a full window.
+type medianTimeRule struct {
+    Meta
+    window []float64
+}
+
+func (r *medianTimeRule) CheckPair(ctx *Context, prev *Entry, e Entry) []Finding {
+    size := ctx.Policy.Timestamps.MedianWindow
+    if !consecutive(prev, e) {
+        r.window = r.window[:0]
+    }
+    var findings []Finding
+    if len(r.window) == size {
+        if mtp := int64(median(r.window)); e.Block.Timestamp <= mtp {
+            findings = found(e, fmt.Sprintf("> %d", mtp), fmt.Sprint(e.Block.Timestamp),
+                "Timestamp not after the median of the last %d blocks", size)
+        }
+    }
+    r.window = append(r.window, float64(e.Block.Timestamp))
+    if len(r.window) > size {
+        r.window = append(r.window[:0], r.window[1:]...)
+    }
+    return findings
+}
+
+func (r *medianTimeRule) Lookback(ctx *Context) int {
+    return ctx.Policy.Timestamps.MedianWindow
+}
+
+// intervalRule flags block intervals far longer than the expected interval,
+// as a stalled chain leaves. Intervals are judged on a log scale, measured
+// in deviations of the recent intervals around the expected one: proof of
+// work spaces blocks exponentially, and only a log scale keeps its long
+// tail from passing for outliers. The deviation is at least a tenth of the
+// expected interval and a second, so a very regular chain is not flagged
+// for ordinary jitter. Short intervals are left to timestamp_cluster, since
+// one quick block is normal under any schedule.
+type intervalRule struct {
+    Meta
+    recent intervals
+}
+
+func (r *intervalRule) CheckPair(ctx *Context, prev *Entry, e Entry) []Finding {
+    if !consecutive(prev, e) {
+        return nil
+    }
+    policy := ctx.Policy.Timestamps
+    interval := float64(e.Block.Timestamp - prev.Block.Timestamp)
+
+    var findings []Finding
+    if center, ok := r.recent.expected(ctx); ok && len(r.recent.values) >= minIntervals {
+        logs := make([]float64, len(r.recent.values))
+        for i, v := range r.recent.values {
+            logs[i] = logInterval(v)
+        }
+        logCenter := logInterval(center)
+        floor := math.Max(math.Log(1.1), math.Log1p(1/math.Max(center, 1)))
+        scale := math.Max(deviation(logs, logCenter, policy.IntervalMethod), floor)
+        high := math.Exp(logCenter + policy.IntervalThreshold*scale)
+        if interval > high {
+            findings = found(e, fmt.Sprintf("<= %.0fs", high), fmt.Sprintf("%.0fs", interval),
+                "Block interval outlier: %.1f deviations (%s, log scale) above %.0fs", (logInterval(interval)-logCenter)/scale, policy.IntervalMethod, center)
+        }
+    }
+    r.recent.add(interval, policy.IntervalWindow)
+    return findings
+}
+
+// logInterval is the log of an interval, counting anything under a second,
+// including the negative intervals timestamp_not_increasing reports, as one.
+func logInterval(seconds float64) float64 {
+    return math.Log(math.Max(seconds, 1))
+}
+
+// Lookback covers a full window of intervals, one more block than intervals.
+func (r *intervalRule) Lookback(ctx *Context) int {
+    return ctx.Policy.Timestamps.IntervalWindow + 1
+}
+
+// deviation is the spread of values around center: the median absolute
+// deviation scaled to match a standard deviation for "mad", the root mean
+// square deviation for "zscore".
+func deviation(values []float64, center float64, method string) float64 {
+    if method == "zscore" {
+        sum := 0.0
+        for _, v := range values {
+            sum += (v - center) * (v - center)
+        }
+        return math.Sqrt(sum / float64(len(values)))
+    }
+    deviations := make([]float64, len(values))
+    for i, v := range values {
+        deviations[i] = math.Abs(v - center)
+    }
+    return 1.4826 * median(deviations)
+}
+
+// clusterRule reports runs of blocks stamped much closer together than the
+// expected interval, as a stalled producer catching up or one gaming
+// timestamps would leave. Each run is reported once, at its first block.
+type clusterRule struct {
+    Meta
+    recent   intervals
+    start    *Entry
+    last     Entry
+    blocks   int
+    expected float64
+}
+
+func (r *clusterRule) CheckPair(ctx *Context, prev *Entry, e Entry) []Finding {
+    if !consecutive(prev, e) {
+        return r.flush(ctx)
+    }
+    policy := ctx.Policy.Timestamps
+    interval := float64(e.Block.Timestamp - prev.Block.Timestamp)
+
+    var findings []Finding
+    if expected, ok := r.recent.expected(ctx); ok && interval < expected*policy.ClusterFraction {
+        if r.start == nil {
+            r.start, r.blocks, r.expected = prev, 1, expected
+        }
+        r.last = e
+        r.blocks++
+    } else {
+        findings = r.flush(ctx)
+    }
+    r.recent.add(interval, policy.IntervalWindow)
+    return findings
+}
+
+func (r *clusterRule) Lookback(ctx *Context) int {
+    return ctx.Policy.Timestamps.IntervalWindow + 1
+}
+
+func (r *clusterRule) Finish(ctx *Context) []Finding {
+    return r.flush(ctx)
+}
+
+// flush ends the current run, reporting it if it is long enough.
+func (r *clusterRule) flush(ctx *Context) []Finding {
+    start := r.start
+    r.start = nil
+    if start == nil || r.blocks < ctx.Policy.Timestamps.ClusterBlocks {
+        return nil
+    }
+    span := r.last.Block.Timestamp - start.Block.Timestamp
+    return []Finding{{
+        Height:    start.Height,
+        BlockHash: start.Block.Hash,
+        Expected:  fmt.Sprintf(">= %.0fs apart", r.expected*ctx.Policy.Timestamps.ClusterFraction),
+        Actual:    fmt.Sprintf("%d blocks in %ds", r.blocks, span),
+        Message:   fmt.Sprintf("Timestamp cluster at heights %d..%d, expected about %.0fs apart", start.Height, r.last.Height, r.expected),
+    }}
+}
@@ -0,0 +1,136 @@
+package validators
+
+import (
+    "crypto/ed25519"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "os"
+    "strings"
+)
+
+// Validator is one allowed block proposer. A validator may propose blocks
+// from ActivationHeight on, and below DeactivationHeight when that is set.
+type Validator struct {
+    Name               string `json:"name,omitempty"`
+    PublicKey          string `json:"public_key"`
+    ActivationHeight   int    `json:"activation_height,omitempty"`
+    DeactivationHeight int    `json:"deactivation_height,omitempty"`
+}
+
+// ActiveAt reports whether the validator may propose the block at height.
+func (v *Validator) ActiveAt(height int) bool {
+    if height < v.ActivationHeight {
+        return false
+    }
+    return v.DeactivationHeight == 0 || height < v.DeactivationHeight
+}
+
+// Label names the validator in reports: its name, else a short key.
+func (v *Validator) Label() string {
+    if v.Name != "" {
+        return v.Name
+    }
+    return v.PublicKey[:16] + "..."
+}
+
+// Set is a validator set file: the public keys allowed to sign blocks.
+type Set struct {
+    Validators []Validator `json:"validators"`
+
+    byKey map[string]*Validator
+}
+
+func LoadSet(path string) (*Set, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read validator set: %w", err)
+    }
+
+    var set Set
+    if err := json.Unmarshal(data, &set); err != nil {
+        return nil, fmt.Errorf("failed to parse validator set: %w", err)
+    }
+    if err := set.index(); err != nil {
+        return nil, fmt.Errorf("validator set %s: %w", path, err)
+    }
+    return &set, nil
+}
+
+// NewSet builds a set from validators, checking their keys.
+func NewSet(validators []Validator) (*Set, error) {
+    set := &Set{Validators: validators}
+    if err := set.index(); err != nil {
+        return nil, err
+    }
+    return set, nil
+}
+
+func (s *Set) index() error {
+    s.byKey = make(map[string]*Validator)
+    for i := range s.Validators {
+        v := &s.Validators[i]
+        v.PublicKey = strings.ToLower(v.PublicKey)
+        key, err := hex.DecodeString(v.PublicKey)
+        if err != nil || len(key) != ed25519.PublicKeySize {
+            return fmt.Errorf("validator %d (%s): public_key is not a hex ed25519 key", i, v.Name)
+        }
+        if _, dup := s.byKey[v.PublicKey]; dup {
+            return fmt.Errorf("validator %d (%s): duplicate public_key", i, v.Name)
+        }
+        s.byKey[v.PublicKey] = v
+    }
+    return nil
+}
+
+// Lookup returns the validator with the given hex public key, or nil.
+func (s *Set) Lookup(publicKey string) *Validator {
+    return s.byKey[strings.ToLower(publicKey)]
+}
+
+func (s *Set) Len() int {
+    return len(s.Validators)
+}
+
+// KeyFile is the on-disk form of a local test signing key.
+type KeyFile struct {
+    PublicKey  string `json:"public_key"`
+    PrivateKey string `json:"private_key"`
+}
+
+// GenerateKey creates a new ed25519 key pair and writes it to path.
+func GenerateKey(path string) (*KeyFile, error) {
+    pub, priv, err := ed25519.GenerateKey(rand.Reader)
+    if err != nil {
+        return nil, err
+    }
+
+    key := &KeyFile{
+        PublicKey:  hex.EncodeToString(pub),
+        PrivateKey: hex.EncodeToString(priv.Seed()),
+    }
+    data, _ := json.MarshalIndent(key, "", "  ")
+    if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+        return nil, fmt.Errorf("failed to write key: %w", err)
+    }
+    return key, nil
+}
+
+// LoadKey reads a key written by GenerateKey.
+func LoadKey(path string) (ed25519.PrivateKey, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read key: %w", err)
+    }
+
+    var key KeyFile
+    if err := json.Unmarshal(data, &key); err != nil {
+        return nil, fmt.Errorf("failed to parse key: %w", err)
+    }
+    seed, err := hex.DecodeString(key.PrivateKey)
+    if err != nil || len(seed) != ed25519.SeedSize {
+        return nil, fmt.Errorf("key %s: private_key is not a hex ed25519 seed", path)
+    }
+    return ed25519.NewKeyFromSeed(seed), nil
+}
@@ -0,0 +1,51 @@
+package validators
+
+import (
+    "crypto/ed25519"
+    "encoding/hex"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestKeyRoundTrip(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "test.key")
+    key, err := GenerateKey(path)
+    if err != nil {
+        t.Fatalf("Failed to generate key: %v", err)
+    }
+
+    priv, err := LoadKey(path)
+    if err != nil {
+        t.Fatalf("Failed to load key: %v", err)
+    }
+    if hex.EncodeToString(priv.Public().(ed25519.PublicKey)) != key.PublicKey {
+        t.Error("Loaded key does not match the generated public key")
+    }
+}
+
+func TestLoadSet(t *testing.T) {
+    pub, _, _ := ed25519.GenerateKey(nil)
+    key := hex.EncodeToString(pub)
+    path := filepath.Join(t.TempDir(), "validators.json")
+    os.WriteFile(path, []byte(`{"validators":[{"name":"v1","public_key":"`+key+`","activation_height":10,"deactivation_height":20}]}`), 0644)
+
+    set, err := LoadSet(path)
+    if err != nil {
+        t.Fatalf("Failed to load set: %v", err)
+    }
+    v := set.Lookup(key)
+    if v == nil {
+        t.Fatal("Expected validator to be found")
+    }
+    if v.ActiveAt(9) || !v.ActiveAt(10) || !v.ActiveAt(19) || v.ActiveAt(20) {
+        t.Error("Activation window is not applied")
+    }
+
+    if _, err := NewSet([]Validator{{PublicKey: key}, {PublicKey: key}}); err == nil {
+        t.Error("Expected duplicate keys to be rejected")
+    }
+    if _, err := NewSet([]Validator{{PublicKey: "abcd"}}); err == nil {
+        t.Error("Expected short key to be rejected")
+    }
+}
@@ -23,7 +23,7 @@ func Watch(rpcURL string, interval int) {
     fmt.Printf("%s╚════════════════════════════════════════════════════════════════╝%s\n", ColorCyan, ColorReset)
     fmt.Printf("\nWatching: %s\n", rpcURL)
     fmt.Printf("Interval: %ds\n", interval)
-    fmt.Println("Press Ctrl+C to stop\n")
+    fmt.Print("Press Ctrl+C to stop\n\n")
     
     ticker := time.NewTicker(time.Duration(interval) * time.Second)
     defer ticker.Stop()